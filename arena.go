@@ -0,0 +1,214 @@
+package generic
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sync"
+	"unsafe"
+)
+
+var ErrArenaPointers = errors.New("type contains pointers and the arena does not allow them")
+var ErrArenaTooLarge = errors.New("allocation is too large for the arena")
+
+const defaultArenaBlockSize = 64 * 1024
+
+// Arena is a bump allocator for values of any type. It hands out memory from
+// large byte blocks, respecting the alignment of each type, and frees all of
+// it at once on Reset.
+//
+// GC safety: the blocks are byte slices, so the garbage collector does not
+// look inside them. A pointer stored in arena memory does NOT keep what it
+// points to alive, and the collector may free it from under you. For that
+// reason, New and MakeSlice panic (and TryNew and TryMakeSlice return
+// ErrArenaPointers) for types that contain pointers, including strings,
+// slices, maps, interfaces and funcs. Set AllowPointers only if every pointer
+// stored in the arena points to memory that's kept alive by other means (for
+// example, into the same arena, or to globals).
+//
+// Pointers into the arena do keep their block alive, but after Reset the
+// memory gets reused, so they must not be used anymore.
+type Arena struct {
+	BlockSize     int
+	AllowPointers bool
+
+	blocks [][]byte // blocks in use; the last one is the one we allocate from
+	spare  [][]byte // blocks kept from before a Reset
+	offset int      // position in the current block
+
+	interned map[string]string
+}
+
+func NewArena() *Arena {
+	return NewArenaSize(defaultArenaBlockSize)
+}
+
+func NewArenaSize(blockSize int) *Arena {
+	Assert(blockSize > 0, "invalid block size")
+	return &Arena{BlockSize: blockSize}
+}
+
+// where zero sized allocations point to
+var arenaZeroBase uint64
+
+// alloc returns zeroed memory of the given size and alignment
+func (a *Arena) alloc(size uintptr, align uintptr) unsafe.Pointer {
+	if size == 0 {
+		return unsafe.Pointer(&arenaZeroBase)
+	}
+	for {
+		if len(a.blocks) > 0 {
+			block := Last(a.blocks)
+			base := uintptr(unsafe.Pointer(unsafe.SliceData(block)))
+			addr := base + uintptr(a.offset)
+			start := (addr+align-1)&^(align-1) - base
+			if start+size <= uintptr(len(block)) {
+				a.offset = int(start + size)
+				return unsafe.Pointer(&block[start])
+			}
+		}
+		a.nextBlock(int(size + align))
+	}
+}
+
+// nextBlock makes a block of at least minSize bytes the current one
+func (a *Arena) nextBlock(minSize int) {
+	var block []byte
+	for i, spare := range a.spare {
+		if len(spare) >= minSize {
+			block = spare
+			RemoveAt(&a.spare, i, 1)
+			break
+		}
+	}
+	if block == nil {
+		block = make([]byte, max(a.BlockSize, minSize))
+	}
+	Append(&a.blocks, block)
+	a.offset = 0
+}
+
+// Reset frees everything allocated from the arena and keeps the blocks for
+// reuse. Nothing previously allocated from it may be used afterwards.
+func (a *Arena) Reset() {
+	for _, block := range a.blocks {
+		clear(block)
+	}
+	Append(&a.spare, a.blocks...)
+	a.blocks = nil
+	a.offset = 0
+	a.interned = nil
+}
+
+// Release resets the arena and drops all its blocks
+func (a *Arena) Release() {
+	a.Reset()
+	a.spare = nil
+}
+
+// Size returns the number of bytes held by the arena's blocks
+func (a *Arena) Size() int {
+	size := 0
+	for _, block := range a.blocks {
+		size += len(block)
+	}
+	for _, block := range a.spare {
+		size += len(block)
+	}
+	return size
+}
+
+var arenaPointerTypes sync.Map // reflect.Type -> bool
+
+func typeHasPointers(t reflect.Type) bool {
+	if cached, ok := arenaPointerTypes.Load(t); ok {
+		return cached.(bool)
+	}
+	var result bool
+	switch t.Kind() {
+	case reflect.Pointer, reflect.UnsafePointer, reflect.Map, reflect.Chan, reflect.Func,
+		reflect.Interface, reflect.Slice, reflect.String:
+		result = true
+	case reflect.Array:
+		result = t.Len() > 0 && typeHasPointers(t.Elem())
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if typeHasPointers(t.Field(i).Type) {
+				result = true
+				break
+			}
+		}
+	}
+	arenaPointerTypes.Store(t, result)
+	return result
+}
+
+func (a *Arena) checkType(t reflect.Type) error {
+	if !a.AllowPointers && typeHasPointers(t) {
+		return fmt.Errorf("%w: %v", ErrArenaPointers, t)
+	}
+	return nil
+}
+
+// TryNew allocates a zero T in the arena
+func TryNew[T any](a *Arena) (*T, error) {
+	if err := a.checkType(reflect.TypeFor[T]()); err != nil {
+		return nil, err
+	}
+	var zero T
+	return (*T)(a.alloc(unsafe.Sizeof(zero), unsafe.Alignof(zero))), nil
+}
+
+// New allocates a zero T in the arena. Panics if T contains pointers and the
+// arena does not allow them.
+func New[T any](a *Arena) *T {
+	return Must(TryNew[T](a))
+}
+
+// TryMakeSlice allocates a slice of n zero items in the arena. The slice's
+// capacity is n; appending beyond that reallocates it on the heap.
+func TryMakeSlice[T any](a *Arena, n int) ([]T, error) {
+	if err := a.checkType(reflect.TypeFor[T]()); err != nil {
+		return nil, err
+	}
+	Assert(n >= 0, "negative slice length")
+	var zero T
+	size, align := unsafe.Sizeof(zero), unsafe.Alignof(zero)
+	// the block for it, alignment padding included, has to fit in an int
+	if size > 0 && uintptr(n) > (math.MaxInt-align)/size {
+		return nil, fmt.Errorf("%w: %d items of %v", ErrArenaTooLarge, n, reflect.TypeFor[T]())
+	}
+	ptr := a.alloc(size*uintptr(n), align)
+	return unsafe.Slice((*T)(ptr), n), nil
+}
+
+// MakeSlice is like TryMakeSlice but panics if T contains pointers and the
+// arena does not allow them, or if the slice is too large
+func MakeSlice[T any](a *Arena, n int) []T {
+	return Must(TryMakeSlice[T](a, n))
+}
+
+// String copies the string into arena memory and returns the copy. Strings
+// are interned: copying the same contents again returns the first copy.
+func (a *Arena) String(s string) string {
+	if s == "" {
+		return ""
+	}
+	if interned, found := a.interned[s]; found {
+		return interned
+	}
+	buf := unsafe.Slice((*byte)(a.alloc(uintptr(len(s)), 1)), len(s))
+	copy(buf, s)
+	interned := UnsafeString(buf)
+	EnsureMapNotNil(&a.interned)
+	a.interned[interned] = interned
+	return interned
+}
+
+// Bytes copies the byte slice into arena memory and returns the copy
+func (a *Arena) Bytes(b []byte) []byte {
+	buf := MakeSlice[byte](a, len(b))
+	copy(buf, b)
+	return buf
+}
@@ -0,0 +1,103 @@
+package generic
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"unsafe"
+)
+
+func isAligned[T any](p *T) bool {
+	var zero T
+	return uintptr(unsafe.Pointer(p))%unsafe.Alignof(zero) == 0
+}
+
+func TestArenaAlignment(t *testing.T) {
+	a := NewArenaSize(256)
+	for range 20 {
+		b := New[byte](a)
+		i := New[int64](a)
+		s := New[struct {
+			a byte
+			b int32
+		}](a)
+		f := New[float64](a)
+		TestExpect(t, isAligned(b) && isAligned(i) && isAligned(s) && isAligned(f), "allocations should be aligned for their type")
+		*b, *i, *f = 1, 2, 3
+	}
+	ints := MakeSlice[int64](a, 10)
+	TestExpect(t, len(ints) == 10 && cap(ints) == 10, "slice should have the requested length and capacity")
+	TestExpect(t, isAligned(&ints[0]), "slice should be aligned for its item type")
+}
+
+func TestArenaBlocks(t *testing.T) {
+	a := NewArenaSize(64)
+	x := MakeSlice[byte](a, 40)
+	y := MakeSlice[byte](a, 40)
+	TestExpectf(t, a.Size() == 128, "the second allocation should start a new block, size is %d", a.Size())
+	TestExpect(t, &x[0] != &y[0], "allocations should not overlap")
+
+	big := MakeSlice[byte](a, 1000)
+	TestExpect(t, len(big) == 1000, "oversized allocation should get its own block")
+	TestExpectf(t, a.Size() >= 128+1000, "oversized block should be counted, size is %d", a.Size())
+
+	for i := range big {
+		big[i] = 0xff
+	}
+	size := a.Size()
+	a.Reset()
+	TestExpectf(t, a.Size() == size, "Reset should keep the blocks, size is %d", a.Size())
+	again := MakeSlice[byte](a, 1000)
+	for _, v := range again {
+		if !TestExpect(t, v == 0, "memory should be zeroed after Reset") {
+			break
+		}
+	}
+	TestExpectf(t, a.Size() == size, "Reset blocks should be reused, size is %d", a.Size())
+
+	a.Release()
+	TestExpect(t, a.Size() == 0, "Release should drop all the blocks")
+}
+
+func TestArenaStrings(t *testing.T) {
+	a := NewArena()
+	s1 := a.String("hello")
+	s2 := a.String(string([]byte("hello")))
+	TestExpect(t, s1 == "hello", "string should be copied")
+	TestExpect(t, unsafe.StringData(s1) == unsafe.StringData(s2), "same contents should be interned")
+	TestExpect(t, a.String("") == "", "empty string should stay empty")
+
+	b := a.Bytes([]byte{1, 2, 3})
+	TestExpect(t, SlicesEqual(b, []byte{1, 2, 3}), "bytes should be copied")
+}
+
+func TestArenaPointers(t *testing.T) {
+	a := NewArena()
+	_, err := TryNew[*int](a)
+	TestExpect(t, errors.Is(err, ErrArenaPointers), "pointer type should be rejected")
+	_, err = TryMakeSlice[struct{ name string }](a, 3)
+	TestExpect(t, errors.Is(err, ErrArenaPointers), "struct with a string should be rejected")
+	_, err = TryNew[[4]int](a)
+	TestExpect(t, err == nil, "array of ints has no pointers")
+
+	panicked := func() (p bool) {
+		defer func() { p = recover() != nil }()
+		New[[]int](a)
+		return
+	}()
+	TestExpect(t, panicked, "New should panic for a pointer type")
+
+	a.AllowPointers = true
+	p, err := TryNew[*int](a)
+	TestExpect(t, err == nil && *p == nil, "AllowPointers should allow pointer types")
+}
+
+func TestArenaTooLarge(t *testing.T) {
+	a := NewArena()
+	_, err := TryMakeSlice[int64](a, math.MaxInt/4)
+	TestExpect(t, errors.Is(err, ErrArenaTooLarge), "overflowing size should be rejected")
+	TestExpect(t, a.Size() == 0, "nothing should be allocated for a rejected slice")
+
+	empty, err := TryMakeSlice[struct{}](a, math.MaxInt)
+	TestExpect(t, err == nil && len(empty) == math.MaxInt, "zero sized items never overflow")
+}
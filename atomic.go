@@ -0,0 +1,237 @@
+package generic
+
+import (
+	"maps"
+	"math/rand/v2"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// Atomic holds a value of any type that can be loaded and stored atomically.
+// It's like atomic.Value but typed. The zero value holds the zero T.
+type Atomic[T any] struct {
+	ptr atomic.Pointer[T]
+}
+
+func NewAtomic[T any](value T) *Atomic[T] {
+	a := new(Atomic[T])
+	a.Store(value)
+	return a
+}
+
+func (a *Atomic[T]) Load() T {
+	if p := a.ptr.Load(); p != nil {
+		return *p
+	}
+	var zero T
+	return zero
+}
+
+func (a *Atomic[T]) Store(value T) {
+	a.ptr.Store(&value)
+}
+
+// Swap stores the new value and returns the old one
+func (a *Atomic[T]) Swap(value T) (old T) {
+	if p := a.ptr.Swap(&value); p != nil {
+		return *p
+	}
+	return
+}
+
+// CompareAndSwap stores new if the current value equals old. Like
+// atomic.Value, it panics if the values are not comparable.
+func (a *Atomic[T]) CompareAndSwap(old, new T) bool {
+	for {
+		p := a.ptr.Load()
+		var current T
+		if p != nil {
+			current = *p
+		}
+		if any(current) != any(old) {
+			return false
+		}
+		if a.ptr.CompareAndSwap(p, &new) {
+			return true
+		}
+	}
+}
+
+// Update atomically replaces the value with fn(current) and returns the new
+// value. fn may be called more than once if other goroutines update the value
+// at the same time, so it must not have side effects.
+func (a *Atomic[T]) Update(fn func(current T) T) T {
+	for {
+		p := a.ptr.Load()
+		var current T
+		if p != nil {
+			current = *p
+		}
+		next := fn(current)
+		if a.ptr.CompareAndSwap(p, &next) {
+			return next
+		}
+	}
+}
+
+// each stripe gets its own cache line so they don't contend with each other
+type counterStripe struct {
+	n atomic.Int64
+	_ [56]byte
+}
+
+// StripedCounter is a counter for hot paths where many goroutines add to it at
+// the same time. Adds go to one of several stripes, picked at random, and
+// Load sums them, so adding is cheap and reading is relatively expensive. The
+// zero value is ready to use, with GOMAXPROCS stripes.
+type StripedCounter struct {
+	once    sync.Once
+	stripes []counterStripe
+}
+
+// NewStripedCounter creates a counter with the given number of stripes; 0 or
+// less picks GOMAXPROCS
+func NewStripedCounter(stripes int) *StripedCounter {
+	c := new(StripedCounter)
+	c.init(stripes)
+	return c
+}
+
+// init allocates the stripes on first use
+func (c *StripedCounter) init(stripes int) {
+	c.once.Do(func() {
+		if stripes <= 0 {
+			stripes = runtime.GOMAXPROCS(0)
+		}
+		c.stripes = make([]counterStripe, stripes)
+	})
+}
+
+func (c *StripedCounter) Add(delta int64) {
+	c.init(0)
+	c.stripes[rand.IntN(len(c.stripes))].n.Add(delta)
+}
+
+func (c *StripedCounter) Inc() {
+	c.Add(1)
+}
+
+// Load returns the sum of the stripes. It's not a snapshot: adds that happen
+// while summing may or may not be counted.
+func (c *StripedCounter) Load() int64 {
+	c.init(0)
+	var total int64
+	for i := range c.stripes {
+		total += c.stripes[i].n.Load()
+	}
+	return total
+}
+
+// Reset sets the counter to zero
+func (c *StripedCounter) Reset() {
+	c.init(0)
+	for i := range c.stripes {
+		c.stripes[i].n.Store(0)
+	}
+}
+
+// AtomicMap is a copy-on-write map for read-mostly data such as configuration.
+// Reads never block; every write copies the whole map. The zero value is an
+// empty map ready to use.
+type AtomicMap[K comparable, V any] struct {
+	current   Atomic[map[K]V]
+	writeLock sync.Mutex
+}
+
+func (m *AtomicMap[K, V]) Get(key K) (V, bool) {
+	value, found := m.current.Load()[key]
+	return value, found
+}
+
+func (m *AtomicMap[K, V]) Len() int {
+	return len(m.current.Load())
+}
+
+// Snapshot returns the current map. It's shared, so it must not be modified.
+func (m *AtomicMap[K, V]) Snapshot() map[K]V {
+	return m.current.Load()
+}
+
+// Update applies fn to a copy of the map and then publishes the copy. Writers
+// are serialized, so fn sees the result of the previous write.
+func (m *AtomicMap[K, V]) Update(fn func(m map[K]V)) {
+	m.writeLock.Lock()
+	defer m.writeLock.Unlock()
+	next := maps.Clone(m.current.Load())
+	EnsureMapNotNil(&next)
+	fn(next)
+	m.current.Store(next)
+}
+
+func (m *AtomicMap[K, V]) Set(key K, value V) {
+	m.Update(func(m map[K]V) {
+		m[key] = value
+	})
+}
+
+func (m *AtomicMap[K, V]) Delete(key K) {
+	m.Update(func(m map[K]V) {
+		delete(m, key)
+	})
+}
+
+// Replace publishes a copy of the given map
+func (m *AtomicMap[K, V]) Replace(data map[K]V) {
+	m.writeLock.Lock()
+	defer m.writeLock.Unlock()
+	m.current.Store(maps.Clone(data))
+}
+
+// AtomicSet is the copy-on-write set counterpart of AtomicMap
+type AtomicSet[T comparable] struct {
+	m AtomicMap[T, struct{}]
+}
+
+func (s *AtomicSet[T]) Has(item T) bool {
+	_, found := s.m.Get(item)
+	return found
+}
+
+func (s *AtomicSet[T]) Len() int {
+	return s.m.Len()
+}
+
+func (s *AtomicSet[T]) Add(items ...T) {
+	s.m.Update(func(m map[T]struct{}) {
+		for _, item := range items {
+			m[item] = struct{}{}
+		}
+	})
+}
+
+func (s *AtomicSet[T]) Remove(items ...T) {
+	s.m.Update(func(m map[T]struct{}) {
+		for _, item := range items {
+			delete(m, item)
+		}
+	})
+}
+
+// Replace sets the contents of the set to the given items
+func (s *AtomicSet[T]) Replace(items []T) {
+	next := make(map[T]struct{}, len(items))
+	for _, item := range items {
+		next[item] = struct{}{}
+	}
+	s.m.Replace(next)
+}
+
+// List returns the items in no particular order
+func (s *AtomicSet[T]) List() []T {
+	out := make([]T, 0, s.Len())
+	for item := range s.m.Snapshot() {
+		Append(&out, item)
+	}
+	return out
+}
@@ -0,0 +1,117 @@
+package generic
+
+import (
+	"sync"
+	"testing"
+)
+
+const (
+	atomicTestGoroutines = 8
+	atomicTestIncrements = 1000
+)
+
+func TestAtomicContention(t *testing.T) {
+	var a Atomic[int]
+	TestExpect(t, a.Load() == 0, "the zero value should hold the zero T")
+	TestExpect(t, a.CompareAndSwap(0, 1) && a.Load() == 1, "CompareAndSwap should work on the zero value")
+	TestExpect(t, !a.CompareAndSwap(0, 5) && a.Load() == 1, "CompareAndSwap should fail when the value differs")
+	TestExpect(t, a.Swap(0) == 1, "Swap should return the old value")
+
+	var wg sync.WaitGroup
+	for range atomicTestGoroutines {
+		WaitGroupGo(&wg, func() {
+			for range atomicTestIncrements {
+				for {
+					current := a.Load()
+					if a.CompareAndSwap(current, current+1) {
+						break
+					}
+				}
+				a.Update(func(current int) int {
+					return current + 1
+				})
+			}
+		})
+	}
+	wg.Wait()
+	expected := 2 * atomicTestGoroutines * atomicTestIncrements
+	TestExpectf(t, a.Load() == expected, "no increment should be lost: %d vs %d", a.Load(), expected)
+
+	s := NewAtomic("hello")
+	TestExpect(t, s.Update(func(current string) string { return current + "!" }) == "hello!", "Update should return the new value")
+}
+
+func TestAtomicMapCopyOnWrite(t *testing.T) {
+	var m AtomicMap[string, int]
+	_, found := m.Get("a")
+	TestExpect(t, !found && m.Len() == 0, "the zero value should be an empty map")
+
+	m.Set("a", 1)
+	m.Set("b", 2)
+	snapshot := m.Snapshot()
+	m.Set("a", 10)
+	m.Delete("b")
+	m.Set("c", 3)
+	TestExpectf(t, len(snapshot) == 2 && snapshot["a"] == 1 && snapshot["b"] == 2, "a taken snapshot must not change: %v", snapshot)
+	value, _ := m.Get("a")
+	TestExpect(t, value == 10 && m.Len() == 2, "writes should be visible in new reads")
+
+	source := map[string]int{"x": 1}
+	m.Replace(source)
+	source["y"] = 2
+	TestExpect(t, m.Len() == 1, "Replace should copy the map it's given")
+
+	var wg sync.WaitGroup
+	for g := range atomicTestGoroutines {
+		WaitGroupGo(&wg, func() {
+			for i := range 100 {
+				m.Update(func(data map[string]int) {
+					data["count"]++
+				})
+				if i == 50 {
+					m.Set(string(rune('a'+g)), g)
+				}
+				m.Snapshot()
+			}
+		})
+	}
+	wg.Wait()
+	count, _ := m.Get("count")
+	TestExpectf(t, count == atomicTestGoroutines*100, "serialized updates should not be lost: %d", count)
+	TestExpect(t, m.Len() == 2+atomicTestGoroutines, "every key should be set")
+}
+
+func TestAtomicSet(t *testing.T) {
+	var s AtomicSet[int]
+	s.Add(1, 2, 3)
+	s.Remove(2)
+	TestExpect(t, s.Has(1) && !s.Has(2) && s.Len() == 2, "unexpected set contents")
+	s.Replace([]int{7, 7, 8})
+	list := s.List()
+	TestExpectf(t, len(list) == 2 && s.Has(7) && s.Has(8) && !s.Has(1), "Replace should set the contents: %v", list)
+}
+
+func TestStripedCounter(t *testing.T) {
+	var zero StripedCounter
+	zero.Inc()
+	zero.Add(4)
+	TestExpect(t, zero.Load() == 5, "the zero value should work")
+
+	for _, c := range []*StripedCounter{&zero, NewStripedCounter(3), NewStripedCounter(0)} {
+		c.Reset()
+		var wg sync.WaitGroup
+		for range atomicTestGoroutines {
+			WaitGroupGo(&wg, func() {
+				for range atomicTestIncrements {
+					c.Inc()
+					c.Add(-2)
+				}
+			})
+		}
+		wg.Wait()
+		expected := int64(-atomicTestGoroutines * atomicTestIncrements)
+		TestExpectf(t, c.Load() == expected, "stripes should sum to %d, got %d", expected, c.Load())
+		c.Reset()
+		TestExpect(t, c.Load() == 0, "Reset should zero every stripe")
+	}
+}
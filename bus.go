@@ -0,0 +1,245 @@
+package generic
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+type DeliveryMode int
+
+const (
+	// the handler is called in the publisher's goroutine, before Publish returns
+	DeliverSync DeliveryMode = iota
+	// messages go through a buffered channel to a goroutine owned by the
+	// subscriber (or to a JobQueue, if one is given), so Publish doesn't wait
+	// for the handler
+	DeliverAsync
+)
+
+// OverflowPolicy decides what happens when an async subscriber's buffer is full
+type OverflowPolicy int
+
+const (
+	OverflowBlock OverflowPolicy = iota // Publish waits for room in the buffer
+	OverflowDrop                        // the message is dropped for that subscriber
+)
+
+const defaultSubscriberBuffer = 64
+
+type SubscribeOptions struct {
+	Mode       DeliveryMode
+	BufferSize int // for DeliverAsync; defaults to 64
+	Overflow   OverflowPolicy
+
+	// for DeliverAsync: run the handler as jobs on this queue instead of on a
+	// dedicated goroutine. The queue is unbounded so there's no overflow, but
+	// messages may be handled concurrently and out of order.
+	Queue *JobQueue
+}
+
+type busMessage[T any] struct {
+	topic string
+	msg   T
+}
+
+// Subscription is returned by Subscribe; use it to unsubscribe
+type Subscription[T any] struct {
+	pattern []string // topic segments to match; nil matches anything
+	handler func(topic string, msg T)
+	opts    SubscribeOptions
+	bus     *Bus[T] // for onPanic, which is read when a handler panics
+
+	ch      chan busMessage[T]
+	done    chan struct{}
+	once    sync.Once
+	dropped atomic.Uint64
+	remove  func()
+}
+
+// Unsubscribe stops delivery to the subscriber. Messages still buffered for an
+// async subscriber are discarded. Safe to call more than once.
+func (s *Subscription[T]) Unsubscribe() {
+	s.once.Do(func() {
+		s.remove()
+		close(s.done)
+	})
+}
+
+// Dropped returns how many messages were dropped because the buffer was full
+func (s *Subscription[T]) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// call runs the handler, isolating the publisher and other subscribers from
+// its panics
+func (s *Subscription[T]) call(m busMessage[T]) {
+	err := callCatchingPanic(func() error {
+		s.handler(m.topic, m.msg)
+		return nil
+	})
+	if err != nil {
+		s.bus.panicked(err.(*PanicError))
+	}
+}
+
+func (s *Subscription[T]) deliver(m busMessage[T]) {
+	select {
+	case <-s.done:
+		return
+	default:
+	}
+	switch {
+	case s.opts.Mode == DeliverSync:
+		s.call(m)
+	case s.opts.Queue != nil:
+		s.opts.Queue.Submit(func() {
+			select {
+			case <-s.done:
+			default:
+				s.call(m)
+			}
+		})
+	case s.opts.Overflow == OverflowDrop:
+		select {
+		case s.ch <- m:
+		case <-s.done:
+		default:
+			s.dropped.Add(1)
+		}
+	default:
+		select {
+		case s.ch <- m:
+		case <-s.done:
+		}
+	}
+}
+
+func (s *Subscription[T]) loop() {
+	for {
+		select {
+		case m := <-s.ch:
+			s.call(m)
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *Subscription[T]) matches(topic []string) bool {
+	if s.pattern == nil {
+		return true
+	}
+	for idx, segment := range s.pattern {
+		if segment == ">" && idx == len(s.pattern)-1 {
+			return len(topic) > idx
+		}
+		if idx >= len(topic) || (segment != "*" && segment != topic[idx]) {
+			return false
+		}
+	}
+	return len(topic) == len(s.pattern)
+}
+
+// Bus delivers messages of type T to the subscribers of their topic. Topics
+// are dot separated, like "orders.created". Subscription patterns can use "*"
+// to match any single segment and ">" at the end to match one or more
+// trailing segments: "orders.*" matches "orders.created", and "orders.>" also
+// matches "orders.items.added".
+//
+// A panicking handler doesn't affect the publisher or other subscribers; the
+// panic is logged, or passed to the function given to SetOnPanic. The zero
+// value is ready to use.
+type Bus[T any] struct {
+	onPanic func(err *PanicError)
+
+	lock sync.RWMutex
+	subs []*Subscription[T]
+}
+
+// SetOnPanic sets where panics from handlers go, instead of the log. It
+// applies to existing subscriptions too, and it's safe to call while messages
+// are being delivered.
+func (b *Bus[T]) SetOnPanic(fn func(err *PanicError)) {
+	WithWriteLock(&b.lock, func() {
+		b.onPanic = fn
+	})
+}
+
+// panicked passes a handler's panic to onPanic, or logs it if there's none
+func (b *Bus[T]) panicked(err *PanicError) {
+	b.lock.RLock()
+	onPanic := b.onPanic
+	b.lock.RUnlock()
+	if onPanic == nil {
+		LogError(err)
+		return
+	}
+	onPanic(err)
+}
+
+func (b *Bus[T]) subscribe(pattern []string, handler func(topic string, msg T), opts SubscribeOptions) *Subscription[T] {
+	s := &Subscription[T]{
+		pattern: pattern,
+		handler: handler,
+		opts:    opts,
+		bus:     b,
+		done:    make(chan struct{}),
+	}
+	s.remove = func() {
+		WithWriteLock(&b.lock, func() {
+			SliceRemove(&b.subs, s)
+		})
+	}
+	if opts.Mode == DeliverAsync && opts.Queue == nil {
+		if opts.BufferSize <= 0 {
+			opts.BufferSize = defaultSubscriberBuffer
+		}
+		s.ch = make(chan busMessage[T], opts.BufferSize)
+		go s.loop()
+	}
+	WithWriteLock(&b.lock, func() {
+		Append(&b.subs, s)
+	})
+	return s
+}
+
+// Subscribe calls handler for every message published to a topic matching
+// the pattern
+func (b *Bus[T]) Subscribe(pattern string, handler func(topic string, msg T), opts SubscribeOptions) *Subscription[T] {
+	return b.subscribe(strings.Split(pattern, "."), handler, opts)
+}
+
+// Publish delivers the message to every subscriber whose pattern matches the
+// topic
+func (b *Bus[T]) Publish(topic string, msg T) {
+	segments := strings.Split(topic, ".")
+	b.lock.RLock()
+	subs := Clone(b.subs)
+	b.lock.RUnlock()
+	for _, s := range subs {
+		if s.matches(segments) {
+			s.deliver(busMessage[T]{topic, msg})
+		}
+	}
+}
+
+// Topic is a Bus with a single topic, for when there's nothing to route on
+type Topic[T any] struct {
+	bus Bus[T]
+}
+
+func (t *Topic[T]) Subscribe(handler func(msg T), opts SubscribeOptions) *Subscription[T] {
+	return t.bus.subscribe(nil, func(topic string, msg T) {
+		handler(msg)
+	}, opts)
+}
+
+func (t *Topic[T]) Publish(msg T) {
+	t.bus.Publish("", msg)
+}
+
+// SetOnPanic is like Bus.SetOnPanic
+func (t *Topic[T]) SetOnPanic(fn func(err *PanicError)) {
+	t.bus.SetOnPanic(fn)
+}
@@ -0,0 +1,178 @@
+package generic
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestBusWildcardsAndPanics(t *testing.T) {
+	var b Bus[int]
+	panics := 0
+	b.SetOnPanic(func(err *PanicError) {
+		panics++
+	})
+	var got []string
+	record := func(tag string) func(topic string, msg int) {
+		return func(topic string, msg int) {
+			Append(&got, tag+":"+topic)
+		}
+	}
+	b.Subscribe("orders.*", record("one"), SubscribeOptions{})
+	b.Subscribe("orders.>", record("tail"), SubscribeOptions{})
+	b.Subscribe("orders.created", func(topic string, msg int) {
+		panic("handler failed")
+	}, SubscribeOptions{})
+	exact := b.Subscribe("orders.created", record("exact"), SubscribeOptions{})
+
+	b.Publish("orders.created", 1)
+	b.Publish("orders.items.added", 2)
+	b.Publish("users.created", 3)
+	exact.Unsubscribe()
+	b.Publish("orders.created", 4)
+
+	expected := []string{
+		"one:orders.created", "tail:orders.created", "exact:orders.created",
+		"tail:orders.items.added",
+		"one:orders.created", "tail:orders.created",
+	}
+	TestExpectf(t, SlicesEqual(got, expected), "unexpected deliveries: %v", got)
+	TestExpectf(t, panics == 2, "expected 2 panics to be reported, got %d", panics)
+}
+
+// receiveN reads n values from ch, failing the test if they take too long
+func receiveN[T any](t *testing.T, ch <-chan T, n int) []T {
+	t.Helper()
+	var out []T
+	timeout := time.After(5 * time.Second)
+	for len(out) < n {
+		select {
+		case v := <-ch:
+			Append(&out, v)
+		case <-timeout:
+			t.Fatalf("timed out after receiving %d of %d values", len(out), n)
+		}
+	}
+	return out
+}
+
+func TestBusAsync(t *testing.T) {
+	var b Bus[int]
+	received := make(chan int, 100)
+	sub := b.Subscribe("tick", func(topic string, msg int) {
+		received <- msg
+	}, SubscribeOptions{Mode: DeliverAsync})
+	defer sub.Unsubscribe()
+
+	expected := make([]int, 100)
+	for i := range expected {
+		expected[i] = i
+		b.Publish("tick", i)
+	}
+	got := receiveN(t, received, 100)
+	TestExpectf(t, SlicesEqual(got, expected), "async delivery should keep the order: %v", got)
+}
+
+func TestBusOverflowDrop(t *testing.T) {
+	var b Bus[int]
+	release := make(chan struct{})
+	received := make(chan int, 10)
+	sub := b.Subscribe("x", func(topic string, msg int) {
+		<-release
+		received <- msg
+	}, SubscribeOptions{Mode: DeliverAsync, BufferSize: 2, Overflow: OverflowDrop})
+	defer sub.Unsubscribe()
+
+	for i := range 10 {
+		b.Publish("x", i) // must not block even though the handler is stuck
+	}
+	dropped := int(sub.Dropped())
+	// the buffer holds 2, and the handler may have taken one more
+	TestExpectf(t, dropped >= 7 && dropped <= 8, "expected 7 or 8 drops, got %d", dropped)
+	close(release)
+	got := receiveN(t, received, 10-dropped)
+	TestExpectf(t, slices.IsSorted(got), "the kept messages should be the first ones in order: %v", got)
+}
+
+func TestBusOverflowBlock(t *testing.T) {
+	var b Bus[int]
+	received := make(chan int, 20)
+	sub := b.Subscribe("x", func(topic string, msg int) {
+		time.Sleep(time.Millisecond)
+		received <- msg
+	}, SubscribeOptions{Mode: DeliverAsync, BufferSize: 1, Overflow: OverflowBlock})
+	defer sub.Unsubscribe()
+
+	for i := range 20 {
+		b.Publish("x", i)
+	}
+	got := receiveN(t, received, 20)
+	TestExpectf(t, len(got) == 20 && slices.IsSorted(got), "every message should be delivered in order: %v", got)
+	TestExpect(t, sub.Dropped() == 0, "nothing should be dropped when blocking")
+}
+
+func TestBusQueue(t *testing.T) {
+	var b Bus[int]
+	panics := make(chan *PanicError, 1)
+	b.SetOnPanic(func(err *PanicError) {
+		panics <- err
+	})
+	received := make(chan int, 50)
+	sub := b.Subscribe("job.*", func(topic string, msg int) {
+		if msg < 0 {
+			panic("negative")
+		}
+		received <- msg
+	}, SubscribeOptions{Mode: DeliverAsync, Queue: MakeJobQueue(3)})
+	defer sub.Unsubscribe()
+
+	for i := range 50 {
+		b.Publish("job.run", i)
+	}
+	b.Publish("job.run", -1)
+	got := receiveN(t, received, 50)
+	slices.Sort(got)
+	for i, v := range got {
+		if !TestExpectf(t, v == i, "every message should be delivered once, got %v", got) {
+			break
+		}
+	}
+	err := receiveN(t, panics, 1)[0]
+	TestExpectf(t, err.Value == "negative", "panics on the queue should go to the SetOnPanic function, got %v", err.Value)
+}
+
+func TestBusOnPanicSetAfterSubscribe(t *testing.T) {
+	var topic Topic[string]
+	topic.Subscribe(func(msg string) {
+		panic(msg)
+	}, SubscribeOptions{})
+
+	var got []any
+	topic.SetOnPanic(func(err *PanicError) {
+		Append(&got, err.Value)
+	})
+	topic.Publish("late")
+	TestExpectf(t, len(got) == 1 && got[0] == "late", "SetOnPanic after Subscribe should apply, got %v", got)
+}
+
+func TestBusSetOnPanicDuringDelivery(t *testing.T) {
+	var b Bus[int]
+	panics := make(chan int, 100)
+	sub := b.Subscribe(">", func(topic string, msg int) {
+		panic(msg)
+	}, SubscribeOptions{Mode: DeliverAsync})
+	defer sub.Unsubscribe()
+	b.SetOnPanic(func(err *PanicError) {})
+
+	// swapping the handler while async deliveries are panicking must not race
+	for i := range 50 {
+		b.Publish("x", i)
+		if i == 25 {
+			b.SetOnPanic(func(err *PanicError) {
+				panics <- err.Value.(int)
+			})
+		}
+	}
+	got := receiveN(t, panics, 1)
+	TestExpectf(t, got[0] >= 0 && got[0] < 50, "the new handler should get later panics: %v", got)
+}
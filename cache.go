@@ -0,0 +1,308 @@
+package generic
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+type EvictionPolicy int
+
+const (
+	EvictLRU EvictionPolicy = iota // evict the least recently used entry
+	EvictLFU                       // evict the least frequently used entry
+)
+
+type EvictReason int
+
+const (
+	EvictedCapacity EvictReason = iota // made room for other entries
+	EvictedExpired                     // its TTL ran out
+)
+
+type CacheOptions[K comparable, V any] struct {
+	MaxEntries int                                      // 0 means no limit
+	MaxCost    int64                                    // 0 means no limit
+	Cost       func(key K, value V) int64               // cost of an entry; defaults to 1
+	Policy     EvictionPolicy                           // which entry to evict when over a limit
+	TTL        time.Duration                            // default TTL for Set; 0 means no expiry
+	OnEvict    func(key K, value V, reason EvictReason) // called without the lock held
+	Now        func() time.Time                         // time source; defaults to time.Now
+}
+
+type CacheStats struct {
+	Hits        uint64
+	Misses      uint64
+	Evictions   uint64 // entries evicted to stay within the limits
+	Expirations uint64 // entries removed because their TTL ran out
+	Entries     int
+	Cost        int64
+}
+
+type cacheEntry[K comparable, V any] struct {
+	key     K
+	value   V
+	cost    int64
+	expires time.Time // zero means never
+
+	// for LFU
+	uses      uint64
+	lastUse   uint64
+	heapIndex int
+}
+
+// lfuHeap orders entries by use count, then by how recently they were used
+type lfuHeap[K comparable, V any] []*cacheEntry[K, V]
+
+func (h lfuHeap[K, V]) Len() int { return len(h) }
+func (h lfuHeap[K, V]) Less(i, j int) bool {
+	if h[i].uses != h[j].uses {
+		return h[i].uses < h[j].uses
+	}
+	return h[i].lastUse < h[j].lastUse
+}
+func (h lfuHeap[K, V]) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].heapIndex = i
+	h[j].heapIndex = j
+}
+func (h *lfuHeap[K, V]) Push(x any) {
+	e := x.(*cacheEntry[K, V])
+	e.heapIndex = len(*h)
+	*h = append(*h, e)
+}
+func (h *lfuHeap[K, V]) Pop() any {
+	old := *h
+	e := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return e
+}
+
+type evictedEntry[K comparable, V any] struct {
+	key    K
+	value  V
+	reason EvictReason
+}
+
+// Cache is an in-memory cache with optional per-entry TTL, bounded by number
+// of entries and/or total cost, evicting by LRU or LFU. Safe for concurrent
+// use. Expired entries are removed lazily when accessed, or explicitly by
+// DeleteExpired; until then they count towards the limits and get evicted by
+// the policy like any other entry.
+type Cache[K comparable, V any] struct {
+	opts CacheOptions[K, V]
+
+	lock    sync.Mutex
+	entries OrderedMap[K, *cacheEntry[K, V]] // ordered from least to most recently used
+	lfu     lfuHeap[K, V]
+	clock   uint64 // counts accesses, to order LFU ties
+	cost    int64
+	stats   CacheStats
+}
+
+func NewCache[K comparable, V any](opts CacheOptions[K, V]) *Cache[K, V] {
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	if opts.Cost == nil {
+		opts.Cost = func(key K, value V) int64 { return 1 }
+	}
+	return &Cache[K, V]{opts: opts}
+}
+
+func (c *Cache[K, V]) touch(e *cacheEntry[K, V]) {
+	c.clock++
+	e.uses++
+	e.lastUse = c.clock
+	if c.opts.Policy == EvictLFU {
+		heap.Fix(&c.lfu, e.heapIndex)
+	} else {
+		c.entries.MoveToBack(e.key)
+	}
+}
+
+func (c *Cache[K, V]) remove(e *cacheEntry[K, V]) {
+	c.entries.Delete(e.key)
+	if c.opts.Policy == EvictLFU {
+		heap.Remove(&c.lfu, e.heapIndex)
+	}
+	c.cost -= e.cost
+}
+
+func (c *Cache[K, V]) expired(e *cacheEntry[K, V], now time.Time) bool {
+	return !e.expires.IsZero() && !now.Before(e.expires)
+}
+
+// victim returns the entry to evict next. The entry that was just set is only
+// picked if it's the only one left; otherwise a new entry under LFU would
+// always evict itself.
+func (c *Cache[K, V]) victim(justSet *cacheEntry[K, V]) *cacheEntry[K, V] {
+	if c.opts.Policy == EvictLFU {
+		if c.lfu[0] != justSet || len(c.lfu) == 1 {
+			return c.lfu[0]
+		}
+		// the next least used entry is one of the root's children
+		if len(c.lfu) > 2 && c.lfu.Less(2, 1) {
+			return c.lfu[2]
+		}
+		return c.lfu[1]
+	}
+	// justSet was moved to the back, so it's only at the front if it's alone
+	_, e, _ := c.entries.Front()
+	return e
+}
+
+func (c *Cache[K, V]) overLimit() bool {
+	return (c.opts.MaxEntries > 0 && c.entries.Len() > c.opts.MaxEntries) ||
+		(c.opts.MaxCost > 0 && c.cost > c.opts.MaxCost)
+}
+
+// notify calls OnEvict for the evicted entries; must be called without the lock
+func (c *Cache[K, V]) notify(evicted []evictedEntry[K, V]) {
+	if c.opts.OnEvict == nil {
+		return
+	}
+	for _, ev := range evicted {
+		c.opts.OnEvict(ev.key, ev.value, ev.reason)
+	}
+}
+
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	var evicted []evictedEntry[K, V]
+	defer func() { c.notify(evicted) }()
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	e, found := c.entries.Get(key)
+	if found && c.expired(e, c.opts.Now()) {
+		c.remove(e)
+		c.stats.Expirations++
+		Append(&evicted, evictedEntry[K, V]{e.key, e.value, EvictedExpired})
+		found = false
+	}
+	if !found {
+		c.stats.Misses++
+		var zero V
+		return zero, false
+	}
+	c.stats.Hits++
+	c.touch(e)
+	return e.value, true
+}
+
+// Set adds or replaces an entry using the default TTL
+func (c *Cache[K, V]) Set(key K, value V) {
+	c.SetWithTTL(key, value, c.opts.TTL)
+}
+
+// SetWithTTL adds or replaces an entry that expires after ttl. A ttl of 0
+// means the entry does not expire.
+//
+// An entry whose cost alone is above MaxCost is not stored, since it could
+// only fit by evicting everything, itself included. OnEvict is called for it
+// right away, and any previous entry for the key is deleted.
+func (c *Cache[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	var evicted []evictedEntry[K, V]
+	defer func() { c.notify(evicted) }()
+
+	cost := c.opts.Cost(key, value)
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	now := c.opts.Now()
+	e, found := c.entries.Get(key)
+	if c.opts.MaxCost > 0 && cost > c.opts.MaxCost {
+		if found {
+			c.remove(e)
+		}
+		c.stats.Evictions++
+		Append(&evicted, evictedEntry[K, V]{key, value, EvictedCapacity})
+		return
+	}
+	if !found {
+		e = &cacheEntry[K, V]{key: key}
+		c.entries.Set(key, e)
+		if c.opts.Policy == EvictLFU {
+			heap.Push(&c.lfu, e)
+		}
+	}
+	c.cost -= e.cost
+	e.value = value
+	e.cost = cost
+	c.cost += e.cost
+	if ttl > 0 {
+		e.expires = now.Add(ttl)
+	} else {
+		e.expires = time.Time{}
+	}
+	c.touch(e)
+
+	for c.overLimit() {
+		victim := c.victim(e)
+		c.remove(victim)
+		reason := EvictedCapacity
+		if c.expired(victim, now) {
+			reason = EvictedExpired
+			c.stats.Expirations++
+		} else {
+			c.stats.Evictions++
+		}
+		Append(&evicted, evictedEntry[K, V]{victim.key, victim.value, reason})
+	}
+}
+
+// Delete removes the entry, without calling OnEvict. Reports whether the
+// entry was present.
+func (c *Cache[K, V]) Delete(key K) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	e, found := c.entries.Get(key)
+	if found {
+		c.remove(e)
+	}
+	return found
+}
+
+// DeleteExpired removes all expired entries and returns how many there were
+func (c *Cache[K, V]) DeleteExpired() int {
+	var evicted []evictedEntry[K, V]
+	defer func() { c.notify(evicted) }()
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	now := c.opts.Now()
+	for _, e := range c.entries.All() {
+		if c.expired(e, now) {
+			c.remove(e)
+			c.stats.Expirations++
+			Append(&evicted, evictedEntry[K, V]{e.key, e.value, EvictedExpired})
+		}
+	}
+	return len(evicted)
+}
+
+// Clear removes all entries, without calling OnEvict
+func (c *Cache[K, V]) Clear() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.entries.Clear()
+	c.lfu = nil
+	c.cost = 0
+}
+
+// Len returns the number of entries, including expired ones that have not
+// been removed yet
+func (c *Cache[K, V]) Len() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.entries.Len()
+}
+
+func (c *Cache[K, V]) Stats() CacheStats {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	stats := c.stats
+	stats.Entries = c.entries.Len()
+	stats.Cost = c.cost
+	return stats
+}
@@ -0,0 +1,98 @@
+package generic
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCacheLRU(t *testing.T) {
+	var evicted []string
+	c := NewCache(CacheOptions[string, int]{
+		MaxEntries: 2,
+		OnEvict: func(key string, value int, reason EvictReason) {
+			Append(&evicted, key)
+		},
+	})
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Get("a")
+	c.Set("c", 3)
+	_, found := c.Get("b")
+	TestExpect(t, !found, "least recently used entry should be evicted")
+	TestExpectf(t, SlicesEqual(evicted, []string{"b"}), "unexpected evictions: %v", evicted)
+
+	stats := c.Stats()
+	TestExpectf(t, stats.Hits == 1 && stats.Misses == 1 && stats.Evictions == 1, "unexpected stats: %+v", stats)
+}
+
+func TestCacheLFU(t *testing.T) {
+	c := NewCache(CacheOptions[string, int]{MaxEntries: 2, Policy: EvictLFU})
+	c.Set("a", 1)
+	c.Get("a")
+	c.Set("b", 2)
+	c.Set("c", 3)
+	_, foundA := c.Get("a")
+	_, foundB := c.Get("b")
+	_, foundC := c.Get("c")
+	TestExpect(t, foundA && !foundB && foundC, "least frequently used entry should be evicted, but not the new one")
+}
+
+func TestCacheTTL(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var reasons []EvictReason
+	c := NewCache(CacheOptions[string, int]{
+		TTL: time.Minute,
+		Now: func() time.Time { return now },
+		OnEvict: func(key string, value int, reason EvictReason) {
+			Append(&reasons, reason)
+		},
+	})
+	c.Set("a", 1)
+	c.SetWithTTL("b", 2, time.Hour)
+	c.SetWithTTL("c", 3, 0)
+
+	now = now.Add(2 * time.Minute)
+	_, found := c.Get("a")
+	TestExpect(t, !found, "entry should expire after the default TTL")
+	TestExpectf(t, SlicesEqual(reasons, []EvictReason{EvictedExpired}), "unexpected evict reasons: %v", reasons)
+
+	now = now.Add(2 * time.Hour)
+	TestExpect(t, c.DeleteExpired() == 1, "entry with a custom TTL should expire")
+	_, found = c.Get("c")
+	TestExpect(t, found, "entry without TTL should not expire")
+}
+
+func TestCacheOversizedEntry(t *testing.T) {
+	for _, policy := range []EvictionPolicy{EvictLRU, EvictLFU} {
+		var evicted []string
+		c := NewCache(CacheOptions[string, int64]{
+			MaxCost: 10,
+			Policy:  policy,
+			Cost: func(key string, value int64) int64 {
+				return value
+			},
+			OnEvict: func(key string, value int64, reason EvictReason) {
+				TestExpect(t, reason == EvictedCapacity, "an oversized entry is evicted for capacity")
+				Append(&evicted, key)
+			},
+		})
+		c.Set("a", 3)
+		c.Set("b", 3)
+		c.Set("c", 3)
+		c.Set("huge", 100)
+		TestExpectf(t, SlicesEqual(evicted, []string{"huge"}), "only the oversized entry should be rejected: %v", evicted)
+		TestExpect(t, c.Len() == 3, "the other entries should stay")
+		_, found := c.Get("huge")
+		TestExpect(t, !found, "the oversized entry should not be stored")
+
+		// replacing an entry with an oversized value drops the old value
+		c.Set("a", 11)
+		_, found = c.Get("a")
+		TestExpect(t, !found, "a stale value should not be left behind")
+		stats := c.Stats()
+		TestExpectf(t, stats.Entries == 2 && stats.Cost == 6, "unexpected stats: %+v", stats)
+
+		c.Set("d", 4) // exactly at the limit is fine
+		TestExpect(t, c.Len() == 3 && c.Stats().Cost == 10, "an entry that fits should be stored without evicting")
+	}
+}
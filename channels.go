@@ -0,0 +1,241 @@
+package generic
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// All the helpers here stop (and close their output channels) when the
+// context is done, so their goroutines don't leak even if the input channels
+// are never closed.
+
+// send sends v on out unless the context is done first
+func send[T any](ctx context.Context, out chan<- T, v T) bool {
+	select {
+	case out <- v:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// OrDone passes through the values from in until it's closed or the context
+// is done
+func OrDone[T any](ctx context.Context, in <-chan T) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case v, ok := <-in:
+				if !ok || !send(ctx, out, v) {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Merge sends the values from all the channels into one, which is closed when
+// all of them are closed
+func Merge[T any](ctx context.Context, chs ...<-chan T) <-chan T {
+	out := make(chan T)
+	var wg sync.WaitGroup
+	for _, ch := range chs {
+		WaitGroupGo(&wg, func() {
+			for {
+				select {
+				case v, ok := <-ch:
+					if !ok || !send(ctx, out, v) {
+						return
+					}
+				case <-ctx.Done():
+					return
+				}
+			}
+		})
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
+
+// FanOut spreads the values from in over n channels; each value goes to
+// whichever output is ready to receive it first. An output that isn't being
+// read holds back at most one value.
+func FanOut[T any](ctx context.Context, in <-chan T, n int) []<-chan T {
+	Assert(n > 0, "FanOut needs at least one output")
+	outs := make([]<-chan T, n)
+	for i := range outs {
+		out := make(chan T)
+		outs[i] = out
+		go func() {
+			defer close(out)
+			for {
+				select {
+				case v, ok := <-in:
+					if !ok || !send(ctx, out, v) {
+						return
+					}
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	return outs
+}
+
+// Tee sends every value from in to both outputs. It waits for both to receive
+// a value before reading the next one.
+func Tee[T any](ctx context.Context, in <-chan T) (<-chan T, <-chan T) {
+	out1 := make(chan T)
+	out2 := make(chan T)
+	go func() {
+		defer close(out1)
+		defer close(out2)
+		for v := range OrDone(ctx, in) {
+			// once one has received, set it to nil so only the other is left
+			o1, o2 := out1, out2
+			for sent := 0; sent < 2; sent++ {
+				select {
+				case o1 <- v:
+					o1 = nil
+				case o2 <- v:
+					o2 = nil
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out1, out2
+}
+
+// Batch groups the values from in into slices of up to size items. A partial
+// batch is sent once maxWait has passed since its first item (0 means wait
+// until it's full), and when in is closed.
+func Batch[T any](ctx context.Context, in <-chan T, size int, maxWait time.Duration) <-chan []T {
+	Assert(size > 0, "invalid batch size")
+	out := make(chan []T)
+	go func() {
+		defer close(out)
+		var batch []T
+		var timer *time.Timer
+		var timeout <-chan time.Time
+		flush := func() bool {
+			if timer != nil {
+				timer.Stop()
+				timeout = nil
+			}
+			if len(batch) == 0 {
+				return true
+			}
+			ok := send(ctx, out, batch)
+			batch = nil
+			return ok
+		}
+		for {
+			select {
+			case v, ok := <-in:
+				if !ok {
+					flush()
+					return
+				}
+				if len(batch) == 0 && maxWait > 0 {
+					if timer == nil {
+						timer = time.NewTimer(maxWait)
+					} else {
+						timer.Reset(maxWait)
+					}
+					timeout = timer.C
+				}
+				Append(&batch, v)
+				if len(batch) >= size && !flush() {
+					return
+				}
+			case <-timeout:
+				timeout = nil
+				if !flush() {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Buffer passes through the values from in with an unbounded buffer in
+// between, so sending on in never blocks for long no matter how slow the
+// receiver is. The buffer is kept in chunks of chunkSize values.
+func Buffer[T any](ctx context.Context, in <-chan T, chunkSize int) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		chqu := createChunkedQueue[T](chunkSize)
+		for in != nil {
+			var pushCh chan T
+			peek, found := chqu.peek()
+			if found {
+				pushCh = out
+			}
+			select {
+			case pushCh <- peek:
+				chqu.consume()
+			case v, ok := <-in:
+				if ok {
+					chqu.push(v)
+				} else {
+					in = nil
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+		// in is closed; drain what's left
+		for {
+			v, found := chqu.peek()
+			if !found || !send(ctx, out, v) {
+				return
+			}
+			chqu.consume()
+		}
+	}()
+	return out
+}
+
+// FirstOf returns the first value received from any of the channels. ok is
+// false if the context is done first, or if all the channels are closed
+// without sending anything.
+func FirstOf[T any](ctx context.Context, chs ...<-chan T) (value T, ok bool) {
+	cases := make([]reflect.SelectCase, 0, len(chs)+1)
+	Append(&cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())})
+	for _, ch := range chs {
+		Append(&cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ch)})
+	}
+	for open := len(chs); open > 0; {
+		chosen, recv, recvOK := reflect.Select(cases)
+		if chosen == 0 {
+			return
+		}
+		if recvOK {
+			// not recv.Interface().(T), which panics when T is an interface
+			// and the value is nil
+			reflect.ValueOf(&value).Elem().Set(recv)
+			return value, true
+		}
+		// closed; stop selecting on it
+		cases[chosen].Chan = reflect.Value{}
+		open--
+	}
+	return
+}
@@ -0,0 +1,150 @@
+package generic
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+// drain reads ch until it's closed, failing the test if that takes too long
+func drain[T any](t *testing.T, ch <-chan T) []T {
+	t.Helper()
+	var out []T
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case v, ok := <-ch:
+			if !ok {
+				return out
+			}
+			Append(&out, v)
+		case <-timeout:
+			t.Fatalf("channel not closed after receiving %d values", len(out))
+		}
+	}
+}
+
+// feed sends the values on a new channel and then closes it
+func feed[T any](values ...T) <-chan T {
+	ch := make(chan T)
+	go func() {
+		defer close(ch)
+		for _, v := range values {
+			ch <- v
+		}
+	}()
+	return ch
+}
+
+func TestFirstOf(t *testing.T) {
+	ctx := context.Background()
+	errs := make(chan error, 1)
+	errs <- nil
+	err, ok := FirstOf(ctx, errs)
+	TestExpect(t, ok && err == nil, "a nil interface value should be received")
+
+	never := make(chan int)
+	value, ok := FirstOf(ctx, never, feed(7))
+	TestExpect(t, ok && value == 7, "the value should come from the channel that has one")
+
+	closed := make(chan int)
+	close(closed)
+	_, ok = FirstOf(ctx, closed, feed[int]())
+	TestExpect(t, !ok, "all channels closed should return false")
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, ok = FirstOf(canceled, never)
+	TestExpect(t, !ok, "a done context should return false")
+}
+
+func TestMerge(t *testing.T) {
+	got := drain(t, Merge(context.Background(), feed(1, 2, 3), feed(4, 5), feed[int]()))
+	slices.Sort(got)
+	TestExpectf(t, SlicesEqual(got, []int{1, 2, 3, 4, 5}), "all values should be merged: %v", got)
+}
+
+func TestFanOut(t *testing.T) {
+	outs := FanOut(context.Background(), feed(0, 1, 2, 3, 4, 5, 6, 7, 8, 9), 2)
+	// only read the first output until it's closed: the other one may hold on
+	// to one value at most
+	first := drain(t, outs[0])
+	TestExpectf(t, len(first) >= 9, "the ready output should get all but one value: %v", first)
+	second := drain(t, outs[1])
+	all := append(first, second...)
+	slices.Sort(all)
+	TestExpectf(t, SlicesEqual(all, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}), "every value should go to one output: %v", all)
+}
+
+func TestTee(t *testing.T) {
+	out1, out2 := Tee(context.Background(), feed(1, 2, 3))
+	got2 := make(chan []int)
+	go func() {
+		var values []int
+		for v := range out2 {
+			Append(&values, v)
+		}
+		got2 <- values
+	}()
+	got1 := drain(t, out1)
+	TestExpectf(t, SlicesEqual(got1, []int{1, 2, 3}), "first output should get every value: %v", got1)
+	TestExpectf(t, SlicesEqual(<-got2, []int{1, 2, 3}), "second output should get every value")
+}
+
+func TestBatch(t *testing.T) {
+	got := drain(t, Batch(context.Background(), feed(0, 1, 2, 3, 4, 5, 6), 3, 0))
+	TestExpectf(t, len(got) == 3 && SlicesEqual(got[0], []int{0, 1, 2}) && SlicesEqual(got[1], []int{3, 4, 5}) &&
+		SlicesEqual(got[2], []int{6}), "unexpected batches: %v", got)
+
+	in := make(chan int)
+	batches := Batch(context.Background(), in, 100, 10*time.Millisecond)
+	in <- 1
+	in <- 2
+	select {
+	case batch := <-batches:
+		TestExpectf(t, SlicesEqual(batch, []int{1, 2}), "partial batch should be sent after maxWait: %v", batch)
+	case <-time.After(5 * time.Second):
+		t.Fatal("partial batch was not sent after maxWait")
+	}
+	close(in)
+	TestExpect(t, len(drain(t, batches)) == 0, "nothing should be left after the timed flush")
+}
+
+func TestBuffer(t *testing.T) {
+	in := make(chan int)
+	out := Buffer(context.Background(), in, 8)
+	for i := range 100 {
+		in <- i // nobody is reading out yet, so this relies on the buffer
+	}
+	close(in)
+	got := drain(t, out)
+	TestExpectf(t, len(got) == 100 && slices.IsSorted(got), "buffered values should come out in order: %v", got)
+}
+
+func TestChannelHelpersStopOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	in := make(chan int) // never closed
+	merged := Merge(ctx, in)
+	outs := FanOut(ctx, in, 2)
+	tee1, tee2 := Tee(ctx, in)
+	batches := Batch(ctx, in, 10, time.Hour)
+	buffered := Buffer(ctx, in, 8)
+	ordone := OrDone(ctx, in)
+	cancel()
+
+	drain(t, merged)
+	drain(t, outs[0])
+	drain(t, outs[1])
+	drain(t, tee1)
+	drain(t, tee2)
+	drain(t, batches)
+	drain(t, buffered)
+	drain(t, ordone)
+
+	select {
+	case in <- 1:
+		t.Error("nothing should be reading the input after cancel")
+	case <-time.After(10 * time.Millisecond):
+	}
+}
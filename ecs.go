@@ -0,0 +1,138 @@
+package generic
+
+// ComponentStore holds components of type C for entities managed by a
+// Manager[E]. It's a sparse set: components are packed densely in one slice
+// (so iterating them is cache friendly) and a sparse slice indexed by the
+// entity's slot points into the dense one.
+//
+// Pointers returned by Add and Get are only valid until the next Add or
+// Remove on the same store, because removal moves the last component into the
+// freed spot.
+type ComponentStore[E, C any] struct {
+	manager  *Manager[E]
+	sparse   []int32 // entity slot -> dense index + 1; 0 means absent
+	dense    []C
+	entities []Handle[E] // parallel to dense
+}
+
+// NewComponentStore creates a component store for entities of the given
+// manager. Components are removed automatically when their entity is deleted.
+func NewComponentStore[E, C any](m *Manager[E]) *ComponentStore[E, C] {
+	s := &ComponentStore[E, C]{manager: m}
+	m.OnDelete(func(handle Handle[E], item *E) {
+		s.Remove(handle)
+	})
+	return s
+}
+
+func (s *ComponentStore[E, C]) denseIndex(entity Handle[E]) int {
+	if entity.slot < 0 || int(entity.slot) >= len(s.sparse) {
+		return -1
+	}
+	idx := int(s.sparse[entity.slot]) - 1
+	if idx < 0 || s.entities[idx] != entity {
+		return -1
+	}
+	return idx
+}
+
+// Add attaches a component to the entity, replacing any existing one, and
+// returns a pointer to the stored component. If the entity has been deleted,
+// nothing is stored and it returns nil.
+func (s *ComponentStore[E, C]) Add(entity Handle[E], component C) *C {
+	if !s.manager.Valid(entity) {
+		return nil
+	}
+	if idx := s.denseIndex(entity); idx != -1 {
+		s.dense[idx] = component
+		return &s.dense[idx]
+	}
+	// a stale entity from the same slot may still have a component here
+	if int(entity.slot) < len(s.sparse) && s.sparse[entity.slot] != 0 {
+		s.removeAt(int(s.sparse[entity.slot]) - 1)
+	}
+	GrowSlice(&s.sparse, int(entity.slot)+1)
+	idx := Append(&s.dense, component)
+	Append(&s.entities, entity)
+	s.sparse[entity.slot] = int32(idx + 1)
+	return &s.dense[idx]
+}
+
+// Get returns the entity's component, or nil if it doesn't have one
+func (s *ComponentStore[E, C]) Get(entity Handle[E]) *C {
+	idx := s.denseIndex(entity)
+	if idx == -1 {
+		return nil
+	}
+	return &s.dense[idx]
+}
+
+func (s *ComponentStore[E, C]) Has(entity Handle[E]) bool {
+	return s.denseIndex(entity) != -1
+}
+
+// Remove detaches the component from the entity, if it has one
+func (s *ComponentStore[E, C]) Remove(entity Handle[E]) {
+	if idx := s.denseIndex(entity); idx != -1 {
+		s.removeAt(idx)
+	}
+}
+
+// removeAt swaps the last component into idx to keep the dense slice packed
+func (s *ComponentStore[E, C]) removeAt(idx int) {
+	last := len(s.dense) - 1
+	removed := s.entities[idx]
+	if idx != last {
+		s.dense[idx] = s.dense[last]
+		s.entities[idx] = s.entities[last]
+		s.sparse[s.entities[idx].slot] = int32(idx + 1)
+	}
+	s.sparse[removed.slot] = 0
+	Reset(&s.dense[last])
+	ShrinkTo(&s.dense, last)
+	ShrinkTo(&s.entities, last)
+}
+
+func (s *ComponentStore[E, C]) Len() int {
+	return len(s.dense)
+}
+
+// Each visits every component in the store in dense order. Stops when visitFn
+// returns false. Don't add or remove components while iterating.
+func (s *ComponentStore[E, C]) Each(visitFn func(entity Handle[E], component *C) bool) {
+	for idx := range s.dense {
+		if !visitFn(s.entities[idx], &s.dense[idx]) {
+			return
+		}
+	}
+}
+
+// Query2 visits every entity that has both an A and a B component. It iterates
+// whichever store is smaller and looks up the other.
+func Query2[E, A, B any](as *ComponentStore[E, A], bs *ComponentStore[E, B], visitFn func(entity Handle[E], a *A, b *B) bool) {
+	if as.Len() <= bs.Len() {
+		as.Each(func(entity Handle[E], a *A) bool {
+			if b := bs.Get(entity); b != nil {
+				return visitFn(entity, a, b)
+			}
+			return true
+		})
+	} else {
+		bs.Each(func(entity Handle[E], b *B) bool {
+			if a := as.Get(entity); a != nil {
+				return visitFn(entity, a, b)
+			}
+			return true
+		})
+	}
+}
+
+// Query3 is like Query2 but for entities with three components
+func Query3[E, A, B, C any](as *ComponentStore[E, A], bs *ComponentStore[E, B], cs *ComponentStore[E, C], visitFn func(entity Handle[E], a *A, b *B, c *C) bool) {
+	Query2(as, bs, func(entity Handle[E], a *A, b *B) bool {
+		if c := cs.Get(entity); c != nil {
+			return visitFn(entity, a, b, c)
+		}
+		return true
+	})
+}
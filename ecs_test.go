@@ -0,0 +1,130 @@
+package generic
+
+import (
+	"testing"
+)
+
+// checkSparse verifies that the sparse slice points at the right dense entries
+func checkSparse[E, C any](t *testing.T, s *ComponentStore[E, C]) {
+	t.Helper()
+	for idx, entity := range s.entities {
+		TestExpectf(t, s.sparse[entity.slot] == int32(idx+1), "sparse entry for slot %d should point at %d", entity.slot, idx)
+	}
+	live := 0
+	for _, idx := range s.sparse {
+		if idx != 0 {
+			live++
+		}
+	}
+	TestExpectf(t, live == len(s.dense), "sparse has %d entries for %d components", live, len(s.dense))
+}
+
+func TestComponentStoreRejectsDeletedEntity(t *testing.T) {
+	m := MakeManager[int]()
+	store := NewComponentStore[int, string](m)
+	_, entity := m.Create()
+	TestExpect(t, store.Add(entity, "alive") != nil, "adding to a live entity should work")
+
+	m.Delete(entity)
+	TestExpect(t, !store.Has(entity), "deleting the entity should remove its component")
+	TestExpect(t, store.Add(entity, "dead") == nil, "adding to a deleted entity should return nil")
+	TestExpect(t, store.Len() == 0, "nothing should be stored for a deleted entity")
+}
+
+func TestComponentStoreSwapRemove(t *testing.T) {
+	m := MakeManager[int]()
+	store := NewComponentStore[int, int](m)
+	var entities []Handle[int]
+	for i := range 6 {
+		_, entity := m.Create()
+		store.Add(entity, i*10)
+		Append(&entities, entity)
+	}
+	store.Remove(entities[1])
+	store.Remove(entities[5]) // the last one
+	m.Delete(entities[0])
+	checkSparse(t, store)
+	TestExpect(t, store.Len() == 3, "three components should be left")
+	for i, entity := range entities {
+		c := store.Get(entity)
+		if i == 0 || i == 1 || i == 5 {
+			TestExpectf(t, c == nil, "component %d should be removed", i)
+		} else {
+			TestExpectf(t, c != nil && *c == i*10, "component %d should survive the swaps", i)
+		}
+	}
+
+	ptr := store.Add(entities[2], 99)
+	TestExpect(t, ptr == store.Get(entities[2]) && *ptr == 99, "Add should replace an existing component in place")
+	TestExpect(t, store.Len() == 3, "replacing should not add a component")
+}
+
+func TestComponentStoreStaleSlot(t *testing.T) {
+	m := MakeManager[int]()
+	// without the OnDelete hook, so the deleted entity's component stays behind
+	store := &ComponentStore[int, string]{manager: m}
+	_, other := m.Create()
+	_, old := m.Create()
+	store.Add(other, "other")
+	store.Add(old, "old")
+	m.Delete(old)
+	_, reused := m.Create()
+	TestExpect(t, reused.slot == old.slot, "the slot should be reused")
+	TestExpect(t, store.Get(reused) == nil, "the new entity should not see the old component")
+
+	store.Add(reused, "new")
+	checkSparse(t, store)
+	TestExpect(t, store.Len() == 2, "the stale component should be replaced, not kept")
+	TestExpect(t, *store.Get(reused) == "new" && *store.Get(other) == "other", "unexpected components after replacing")
+	TestExpect(t, store.Get(old) == nil, "the old handle should not find anything")
+}
+
+func TestQuery(t *testing.T) {
+	m := MakeManager[string]()
+	positions := NewComponentStore[string, int](m)
+	velocities := NewComponentStore[string, int](m)
+	names := NewComponentStore[string, string](m)
+	entities := make(map[string]Handle[string])
+	for _, name := range []string{"rock", "ship", "ghost", "bird"} {
+		_, entity := m.Create()
+		entities[name] = entity
+		names.Add(entity, name)
+	}
+	for _, name := range []string{"rock", "ship", "bird"} {
+		positions.Add(entities[name], 1)
+	}
+	for _, name := range []string{"ship", "ghost", "bird"} {
+		velocities.Add(entities[name], 2)
+	}
+
+	moved := NewSet[string]()
+	Query2(positions, velocities, func(entity Handle[string], p *int, v *int) bool {
+		*p += *v
+		moved.Add(*names.Get(entity))
+		return true
+	})
+	TestExpectf(t, SetsEqual(moved, NewSetFrom([]string{"ship", "bird"})), "Query2 should visit entities with both components: %v", moved.ToSlice())
+	TestExpect(t, *positions.Get(entities["ship"]) == 3 && *positions.Get(entities["rock"]) == 1, "Query2 should give pointers into the stores")
+
+	var seen []string
+	Query3(names, positions, velocities, func(entity Handle[string], name *string, p *int, v *int) bool {
+		Append(&seen, *name)
+		return true
+	})
+	TestExpectf(t, len(seen) == 2, "Query3 should visit entities with all three components: %v", seen)
+
+	visits := 0
+	Query3(velocities, names, positions, func(entity Handle[string], v *int, name *string, p *int) bool {
+		visits++
+		return false
+	})
+	TestExpect(t, visits == 1, "returning false should stop the query")
+
+	m.Delete(entities["ship"])
+	seen = nil
+	Query2(positions, velocities, func(entity Handle[string], p *int, v *int) bool {
+		Append(&seen, *names.Get(entity))
+		return true
+	})
+	TestExpectf(t, SlicesEqual(seen, []string{"bird"}), "deleted entities should drop out of queries: %v", seen)
+}
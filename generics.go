@@ -5,8 +5,12 @@ as well as "generic" utility functions (the other meaning of generic).
 package generic
 
 import (
+	"iter"
 	"slices"
+	"sort"
+	"sync"
 	"testing"
+	"unsafe"
 )
 
 // Last returns the last element from the list
@@ -324,34 +328,96 @@ func MulDiv[T Inty](a, b, c T) T {
 }
 
 type TypedBucket[T any] struct {
-	Items      [4 * 1024]T
+	Items      []T
 	Next       int
 	NextBucket *TypedBucket[T]
 }
 
+func newTypedBucket[T any](size int) *TypedBucket[T] {
+	return &TypedBucket[T]{Items: make([]T, size)}
+}
+
 type TypedArena[T any] struct {
 	First   *TypedBucket[T]
 	Current *TypedBucket[T]
+
+	BucketSize int // number of items per bucket
+
+	// directory of the buckets in use (First up to Current) and the index of
+	// the first item in each, for random access
+	buckets []*TypedBucket[T]
+	starts  []int
 }
 
+// AutoBucketSize picks a bucket size for T so that each bucket takes about
+// 64KB, within reasonable bounds on the number of items
+func AutoBucketSize[T any]() int {
+	var zero T
+	size := int(unsafe.Sizeof(zero))
+	if size == 0 {
+		return 4 * 1024
+	}
+	return min(max(64*1024/size, 64), 64*1024)
+}
+
+// NewTypedArena creates an arena with the bucket size picked by AutoBucketSize
 func NewTypedArena[T any]() *TypedArena[T] {
-	bucket := new(TypedBucket[T])
+	return NewTypedArenaSize[T](AutoBucketSize[T]())
+}
+
+// NewTypedArenaSize creates an arena whose buckets hold bucketSize items each
+func NewTypedArenaSize[T any](bucketSize int) *TypedArena[T] {
+	Assert(bucketSize > 0, "invalid bucket size")
+	bucket := newTypedBucket[T](bucketSize)
 	return &TypedArena[T]{
-		First:   bucket,
-		Current: bucket,
+		First:      bucket,
+		Current:    bucket,
+		BucketSize: bucketSize,
+		buckets:    []*TypedBucket[T]{bucket},
+		starts:     []int{0},
+	}
+}
+
+// advance moves to the next bucket, making sure it has room for n items.
+// Buckets kept from before a Reset get reused if they are big enough.
+func (a *TypedArena[T]) advance(n int) {
+	next := a.Current.NextBucket
+	if next == nil || len(next.Items) < n {
+		bucket := newTypedBucket[T](max(a.BucketSize, n))
+		bucket.NextBucket = next
+		a.Current.NextBucket = bucket
+		next = bucket
 	}
+	Append(&a.starts, Last(a.starts)+a.Current.Next)
+	Append(&a.buckets, next)
+	a.Current = next
 }
 
 func (a *TypedArena[T]) Allocate() *T {
 	if a.Current.Next >= len(a.Current.Items) {
-		a.Current.NextBucket = new(TypedBucket[T])
-		a.Current = a.Current.NextBucket
+		a.advance(1)
 	}
 	item := &a.Current.Items[a.Current.Next]
 	a.Current.Next++
 	return item
 }
 
+// AllocateN allocates n items that are contiguous in memory (from one bucket).
+// If they don't fit in the rest of the current bucket, the rest is skipped. If
+// n is bigger than the bucket size, a dedicated bucket is allocated for them.
+func (a *TypedArena[T]) AllocateN(n int) []T {
+	if n <= 0 {
+		return nil
+	}
+	if len(a.Current.Items)-a.Current.Next < n {
+		a.advance(n)
+	}
+	start := a.Current.Next
+	a.Current.Next += n
+	// cap the slice so appending to it can't overwrite other allocations
+	return a.Current.Items[start:a.Current.Next:a.Current.Next]
+}
+
 func (a *TypedArena[T]) Iterate(visitFn func(index int, item *T) bool) {
 	bucket := a.First
 	index := 0
@@ -368,58 +434,92 @@ func (a *TypedArena[T]) Iterate(visitFn func(index int, item *T) bool) {
 	}
 }
 
-func (a *TypedArena[T]) IterateBuckets(visitFn func(items []T)) {
-	bucket := a.First
-	for bucket != nil {
-		slice := bucket.Items[:bucket.Next]
-		visitFn(slice)
-		bucket = bucket.NextBucket
+// Len returns the number of items allocated in the arena
+func (a *TypedArena[T]) Len() int {
+	return Last(a.starts) + a.Current.Next
+}
+
+// At returns the item at the given index, as passed by Iterate. It's O(1)
+// unless AllocateN has skipped bucket space or made oversized buckets, in which
+// case it falls back to a binary search over the buckets.
+func (a *TypedArena[T]) At(index int) *T {
+	Assert(index >= 0 && index < a.Len(), "arena index out of range")
+	b := index / a.BucketSize
+	if b >= len(a.starts) || a.starts[b] > index || index-a.starts[b] >= a.buckets[b].Next {
+		b = sort.Search(len(a.starts), func(i int) bool {
+			return a.starts[i] > index
+		}) - 1
 	}
+	return &a.buckets[b].Items[index-a.starts[b]]
 }
 
-type Set[T comparable] struct {
-	Map map[T]bool
+// All returns an iterator over the items and their indices, for use with
+// `for index, item := range arena.All()`
+func (a *TypedArena[T]) All() iter.Seq2[int, *T] {
+	return func(yield func(int, *T) bool) {
+		a.Iterate(yield)
+	}
 }
 
-func NewSet[T comparable]() *Set[T] {
-	return &Set[T]{
-		Map: make(map[T]bool),
+// Values returns an iterator over the items
+func (a *TypedArena[T]) Values() iter.Seq[*T] {
+	return func(yield func(*T) bool) {
+		a.Iterate(func(index int, item *T) bool {
+			return yield(item)
+		})
 	}
 }
 
-func NewSetFrom[T comparable](items []T) *Set[T] {
-	set := NewSet[T]()
-	set.Add(items...)
-	return set
+func (a *TypedArena[T]) IterateBuckets(visitFn func(items []T)) {
+	bucket := a.First
+	for bucket != nil {
+		slice := bucket.Items[:bucket.Next]
+		visitFn(slice)
+		bucket = bucket.NextBucket
+	}
 }
 
-func (s *Set[T]) Add(items ...T) {
-	for _, item := range items {
-		s.Map[item] = true
+// Reset rewinds the arena so its buckets get reused by subsequent allocations.
+// All pointers previously handed out by the arena must no longer be used. If
+// zero is false, the items are not cleared, so Allocate may return items
+// holding stale data from before the reset.
+func (a *TypedArena[T]) Reset(zero bool) {
+	for bucket := a.First; bucket != nil; bucket = bucket.NextBucket {
+		if zero {
+			clear(bucket.Items[:bucket.Next])
+		}
+		bucket.Next = 0
 	}
+	a.Current = a.First
+	ShrinkTo(&a.buckets, 1)
+	ShrinkTo(&a.starts, 1)
 }
 
-func (s *Set[T]) Has(item T) bool {
-	exists, _ := s.Map[item]
-	return exists
+// Release resets the arena and drops all buckets except the first, so their
+// memory can be reclaimed
+func (a *TypedArena[T]) Release() {
+	a.First.NextBucket = nil
+	a.Reset(true)
 }
 
-func (s *Set[T]) Remove(item T) {
-	delete(s.Map, item)
+// TypedArenaPool hands out arenas for request scoped (or frame scoped) use, so
+// their buckets can be reused across requests instead of becoming garbage
+type TypedArenaPool[T any] struct {
+	pool sync.Pool
 }
 
-func SetsEqual[T comparable](s1 *Set[T], s2 *Set[T]) bool {
-	for item := range s1.Map {
-		if !s2.Has(item) {
-			return false
-		}
-	}
-	for item := range s2.Map {
-		if !s1.Has(item) {
-			return false
-		}
+func (p *TypedArenaPool[T]) Get() *TypedArena[T] {
+	if a, ok := p.pool.Get().(*TypedArena[T]); ok {
+		return a
 	}
-	return true
+	return NewTypedArena[T]()
+}
+
+// Put resets the arena and returns it to the pool. The arena must not be used
+// after putting it back.
+func (p *TypedArenaPool[T]) Put(a *TypedArena[T]) {
+	a.Reset(true)
+	p.pool.Put(a)
 }
 
 // Get a map entry with a function to create it if not existing
@@ -0,0 +1,124 @@
+package generic
+
+import (
+	"testing"
+)
+
+// checkArenaIndices verifies that At agrees with Iterate for every item
+func checkArenaIndices[T any](t *testing.T, a *TypedArena[T]) {
+	t.Helper()
+	count := 0
+	a.Iterate(func(index int, item *T) bool {
+		TestExpectf(t, index == count, "Iterate index %d should be %d", index, count)
+		TestExpectf(t, a.At(index) == item, "At(%d) should match Iterate", index)
+		count++
+		return true
+	})
+	TestExpectf(t, count == a.Len(), "Len is %d but Iterate visited %d items", a.Len(), count)
+}
+
+func TestTypedArenaAllocateN(t *testing.T) {
+	a := NewTypedArenaSize[int](8)
+	for i := range 5 {
+		*a.Allocate() = i
+	}
+	// doesn't fit in the 3 items left, so they get skipped
+	run := a.AllocateN(4)
+	TestExpect(t, len(run) == 4 && cap(run) == 4, "AllocateN should return exactly n items")
+	for i := range run {
+		run[i] = 100 + i
+	}
+	// bigger than a bucket, so it gets its own
+	big := a.AllocateN(20)
+	for i := range big {
+		big[i] = 200 + i
+	}
+	*a.Allocate() = 300
+	TestExpect(t, &run[0] == a.At(5) && &run[3] == a.At(8), "AllocateN items should be the arena's own")
+
+	TestExpectf(t, a.Len() == 5+4+20+1, "unexpected Len: %d", a.Len())
+	checkArenaIndices(t, a)
+	TestExpect(t, *a.At(4) == 4 && *a.At(5) == 100, "indices should skip the unused space")
+	TestExpect(t, *a.At(9) == 200 && *a.At(28) == 219, "the oversized bucket should be indexed")
+	TestExpect(t, *a.At(29) == 300, "allocations after the oversized bucket should be indexed")
+
+	run = append(run, 999)
+	TestExpect(t, *a.At(9) == 200, "appending to an AllocateN slice should not overwrite the arena")
+	TestExpect(t, a.AllocateN(0) == nil, "AllocateN(0) should allocate nothing")
+}
+
+func TestTypedArenaIndexing(t *testing.T) {
+	a := NewTypedArenaSize[int](4)
+	TestExpect(t, a.Len() == 0, "a new arena should be empty")
+	for i := range 10 {
+		*a.Allocate() = i
+	}
+	checkArenaIndices(t, a)
+	for i := range 10 {
+		TestExpectf(t, *a.At(i) == i, "At(%d) should use the fast path", i)
+	}
+
+	var fromAll []int
+	for index, item := range a.All() {
+		TestExpect(t, index == len(fromAll), "All should yield consecutive indices")
+		Append(&fromAll, *item)
+		if index == 6 {
+			break
+		}
+	}
+	TestExpectf(t, SlicesEqual(fromAll, []int{0, 1, 2, 3, 4, 5, 6}), "breaking out of All should work: %v", fromAll)
+	var fromValues []int
+	for item := range a.Values() {
+		Append(&fromValues, *item)
+	}
+	TestExpect(t, len(fromValues) == 10 && fromValues[9] == 9, "Values should yield every item")
+}
+
+func TestTypedArenaReset(t *testing.T) {
+	a := NewTypedArenaSize[int](4)
+	for i := range 6 {
+		*a.Allocate() = i
+	}
+	a.AllocateN(10)
+	a.Allocate()
+	buckets := 0
+	for b := a.First; b != nil; b = b.NextBucket {
+		buckets++
+	}
+
+	a.Reset(false)
+	TestExpect(t, a.Len() == 0, "Reset should empty the arena")
+	TestExpect(t, *a.Allocate() == 0, "without zeroing, the first item keeps its old value")
+	for range 5 {
+		a.Allocate()
+	}
+	run := a.AllocateN(10) // skips the rest of the second bucket, like before
+	*a.Allocate() = 42
+	TestExpectf(t, a.Len() == 6+10+1, "unexpected Len after Reset: %d", a.Len())
+	checkArenaIndices(t, a)
+	TestExpect(t, a.At(6) == &run[0] && *a.At(16) == 42, "indices should be consistent after Reset")
+	reused := 0
+	for b := a.First; b != nil; b = b.NextBucket {
+		reused++
+	}
+	TestExpectf(t, reused == buckets, "Reset should reuse the buckets instead of adding more: %d vs %d", reused, buckets)
+
+	a.Reset(true)
+	TestExpect(t, *a.Allocate() == 0, "zeroing Reset should clear the items")
+	checkArenaIndices(t, a)
+
+	a.Release()
+	TestExpect(t, a.First.NextBucket == nil && a.Len() == 0, "Release should keep only the first bucket")
+	for i := range 9 {
+		*a.Allocate() = i
+	}
+	checkArenaIndices(t, a)
+	TestExpect(t, *a.At(8) == 8, "the arena should grow again after Release")
+
+	var pool TypedArenaPool[int]
+	pooled := pool.Get()
+	*pooled.Allocate() = 5
+	pool.Put(pooled)
+	again := pool.Get()
+	TestExpect(t, again.Len() == 0, "arenas from the pool should be empty")
+}
@@ -1,3 +1,3 @@
 module go.hasen.dev/generic
 
-go 1.21
+go 1.24
@@ -1,7 +1,10 @@
 package generic
 
 import (
-	"log"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"slices"
 )
 
 type Handle[T any] struct {
@@ -9,11 +12,56 @@ type Handle[T any] struct {
 	gen  int32 // generation number!
 }
 
+// managerChunkSize is the number of items per chunk for chunked managers
+const managerChunkSize = 1024
+
+var (
+	ErrInvalidHandle = errors.New("invalid handle")
+	ErrHandleDeleted = errors.New("handle already deleted")
+)
+
+// HandleError is returned by TryGet and TryDelete. When the manager is in debug
+// mode, it carries the stacks of where the handle was created and deleted.
+type HandleError struct {
+	Err         error // ErrInvalidHandle or ErrHandleDeleted
+	CreateStack []byte
+	DeleteStack []byte
+}
+
+func (e *HandleError) Error() string {
+	msg := e.Err.Error()
+	if e.CreateStack != nil {
+		msg += "\ncreated at:\n" + string(e.CreateStack)
+	}
+	if e.DeleteStack != nil {
+		msg += "\ndeleted at:\n" + string(e.DeleteStack)
+	}
+	return msg
+}
+
+func (e *HandleError) Unwrap() error {
+	return e.Err
+}
+
+type handleTrace struct {
+	createStack []byte
+	deleteStack []byte
+}
+
 type Manager[T any] struct {
 	items     []T
+	chunks    [][]T // used instead of items when the manager is chunked
+	chunked   bool
 	gens      []int32
 	freeSlots []int32
 	nextGen   int32
+	resetGen  int32 // every generation up to this one was issued before the last Reset
+	resetting bool
+
+	onDelete []func(handle Handle[T], item *T)
+
+	// only populated in debug mode; never pruned, so don't leave it on in production
+	traces map[Handle[T]]*handleTrace
 }
 
 func MakeManager[T any]() *Manager[T] {
@@ -24,6 +72,37 @@ func MakeManager[T any]() *Manager[T] {
 	}
 }
 
+// MakeChunkedManager creates a manager that stores items in fixed size chunks
+// (like TypedBucket) instead of one growing slice. Pointers returned by Create
+// and GetItem stay valid until the item is deleted, because the storage never
+// gets reallocated.
+func MakeChunkedManager[T any]() *Manager[T] {
+	return &Manager[T]{
+		chunked:   true,
+		gens:      make([]int32, 0, 1024),
+		freeSlots: make([]int32, 0, 128),
+	}
+}
+
+func (m *Manager[T]) itemAt(slot int32) *T {
+	if m.chunked {
+		return &m.chunks[slot/managerChunkSize][slot%managerChunkSize]
+	}
+	return &m.items[slot]
+}
+
+// appendSlot adds storage for a new slot at the end and returns a pointer to it
+func (m *Manager[T]) appendSlot() *T {
+	if !m.chunked {
+		return AllocAppend(&m.items)
+	}
+	slot := int32(len(m.gens) - 1)
+	if int(slot/managerChunkSize) >= len(m.chunks) {
+		Append(&m.chunks, make([]T, managerChunkSize))
+	}
+	return m.itemAt(slot)
+}
+
 func (m *Manager[T]) Create() (itemPtr *T, handle Handle[T]) {
 	m.nextGen++
 	if len(m.freeSlots) > 0 {
@@ -33,41 +112,180 @@ func (m *Manager[T]) Create() (itemPtr *T, handle Handle[T]) {
 		m.gens[slot] = m.nextGen
 		handle.slot = slot
 		handle.gen = m.nextGen
-		itemPtr = &m.items[slot]
-		return
+		itemPtr = m.itemAt(slot)
 	} else {
 		Append(&m.gens, m.nextGen)
-		itemPtr = AllocAppend(&m.items)
-		handle.slot = int32(len(m.items) - 1)
+		itemPtr = m.appendSlot()
+		handle.slot = int32(len(m.gens) - 1)
 		handle.gen = m.nextGen
-		return
 	}
+	if m.traces != nil {
+		m.traces[handle] = &handleTrace{createStack: debug.Stack()}
+	}
+	return
 }
 
 func (m *Manager[T]) valid(handle Handle[T]) bool {
-	return handle.slot >= 0 && handle.slot < int32(len(m.gens)) && m.gens[handle.slot] == handle.gen
+	// gen 0 marks a free slot, so the zero handle is never valid
+	return handle.gen != 0 && handle.slot >= 0 && handle.slot < int32(len(m.gens)) && m.gens[handle.slot] == handle.gen
 }
 
-func (m *Manager[T]) Delete(handle Handle[T]) {
+// Valid reports whether the handle still refers to a live item. A handle is
+// effectively a weak reference: holding it does not keep the item alive.
+func (m *Manager[T]) Valid(handle Handle[T]) bool {
+	return m.valid(handle)
+}
+
+// SetDebug turns on recording of the stacks where each handle was created and
+// deleted, so that errors from TryGet and TryDelete can say where a stale
+// handle came from. It's meant for diagnosing use-after-delete bugs.
+func (m *Manager[T]) SetDebug(on bool) {
+	if on {
+		EnsureMapNotNil(&m.traces)
+	} else {
+		m.traces = nil
+	}
+}
+
+// OnDelete registers a callback that gets called whenever an item is deleted,
+// before its storage is cleared. By the time it's called, the handle is no
+// longer valid, but the item pointer still holds the item's data.
+func (m *Manager[T]) OnDelete(fn func(handle Handle[T], item *T)) {
+	Append(&m.onDelete, fn)
+}
+
+func (m *Manager[T]) handleError(handle Handle[T]) error {
+	herr := &HandleError{Err: ErrInvalidHandle}
+	// slots are never handed out beyond len(gens), and generations are never
+	// reused, so a handle with a known slot must have been deleted at some point.
+	// Handles from before the last Reset were deleted by it, whatever their slot.
+	if handle.gen != 0 && handle.gen <= m.nextGen && handle.slot >= 0 &&
+		(handle.slot < int32(len(m.gens)) || handle.gen <= m.resetGen) {
+		herr.Err = ErrHandleDeleted
+	}
+	if trace := m.traces[handle]; trace != nil {
+		herr.Err = ErrHandleDeleted
+		herr.CreateStack = trace.createStack
+		herr.DeleteStack = trace.deleteStack
+	}
+	return herr
+}
+
+// TryDelete deletes the item the handle refers to, or returns a *HandleError if
+// the handle is not valid
+func (m *Manager[T]) TryDelete(handle Handle[T]) error {
 	if !m.valid(handle) {
-		log.Println("WARNING: handle already deleted!")
-		return
+		return m.handleError(handle)
 	}
-	Append(&m.freeSlots, handle.slot)
+	item := m.itemAt(handle.slot)
+	// mark the slot dead before the callbacks run, so a callback that deletes
+	// the same handle gets an error instead of recursing. The slot only becomes
+	// free for reuse after the callbacks are done with the item.
 	Reset(&m.gens[handle.slot])
-	Reset(&m.items[handle.slot])
+	if trace := m.traces[handle]; trace != nil {
+		trace.deleteStack = debug.Stack()
+	}
+	for _, fn := range m.onDelete {
+		fn(handle, item)
+	}
+	Append(&m.freeSlots, handle.slot)
+	Reset(item)
+	return nil
+}
+
+// Delete is like TryDelete but only logs the error
+func (m *Manager[T]) Delete(handle Handle[T]) {
+	if err := m.TryDelete(handle); err != nil {
+		LogError(fmt.Errorf("WARNING: %w", err))
+	}
+}
+
+// TryGet returns the item the handle refers to, or a *HandleError if the
+// handle is not valid
+func (m *Manager[T]) TryGet(handle Handle[T]) (*T, error) {
+	if !m.valid(handle) {
+		return nil, m.handleError(handle)
+	}
+	return m.itemAt(handle.slot), nil
 }
 
 func (m *Manager[T]) GetItem(handle Handle[T]) *T {
 	if !m.valid(handle) {
 		return nil
 	} else {
-		return &m.items[handle.slot]
+		return m.itemAt(handle.slot)
 	}
 }
 
+// Reset deletes all items. Every handle issued before the reset becomes
+// invalid, even after new items reuse the same slots, and TryGet and TryDelete
+// report them as deleted. OnDelete callbacks are called for every live item.
 func (m *Manager[T]) Reset() {
+	if len(m.onDelete) > 0 {
+		m.resetting = true
+		defer func() { m.resetting = false }()
+		for slot, gen := range m.gens {
+			if gen != 0 {
+				m.TryDelete(Handle[T]{slot: int32(slot), gen: gen})
+			}
+		}
+	}
+	// generations are never reused, so bumping past everything issued so far is
+	// enough to keep old handles from matching new items in the same slots
+	m.nextGen++
+	m.resetGen = m.nextGen
 	ResetSlice(&m.items)
+	clear(m.chunks) // let the chunks be collected
+	ResetSlice(&m.chunks)
 	ResetSlice(&m.gens)
 	ResetSlice(&m.freeSlots)
 }
+
+// Resetting reports whether the manager is in the middle of Reset. OnDelete
+// callbacks can check it to skip work that only makes sense when a single item
+// is deleted, since everything else is going away too.
+func (m *Manager[T]) Resetting() bool {
+	return m.resetting
+}
+
+// Compact trims the free slots at the end of the storage and releases the
+// memory they (and any excess capacity) occupy. Handles of live items remain
+// valid. For non chunked managers, item pointers are invalidated.
+func (m *Manager[T]) Compact() {
+	n := len(m.gens)
+	for n > 0 && m.gens[n-1] == 0 {
+		n--
+	}
+	m.freeSlots = slices.DeleteFunc(m.freeSlots, func(slot int32) bool {
+		return int(slot) >= n
+	})
+
+	m.gens = slices.Clone(m.gens[:n])
+	m.freeSlots = slices.Clone(m.freeSlots)
+	if m.chunked {
+		chunkCount := (n + managerChunkSize - 1) / managerChunkSize
+		clear(m.chunks[chunkCount:])
+		m.chunks = slices.Clone(m.chunks[:chunkCount])
+	} else {
+		m.items = slices.Clone(m.items[:n])
+	}
+}
+
+type ManagerStats struct {
+	Live     int // number of items currently alive
+	Free     int // number of slots available for reuse
+	Capacity int // number of items that fit in the allocated storage
+}
+
+func (m *Manager[T]) Stats() ManagerStats {
+	stats := ManagerStats{
+		Live: len(m.gens) - len(m.freeSlots),
+		Free: len(m.freeSlots),
+	}
+	if m.chunked {
+		stats.Capacity = len(m.chunks) * managerChunkSize
+	} else {
+		stats.Capacity = cap(m.items)
+	}
+	return stats
+}
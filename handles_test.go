@@ -0,0 +1,109 @@
+package generic
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestManagerDeleteFromOnDelete(t *testing.T) {
+	m := MakeManager[int]()
+	var errs []error
+	var seen []int
+	m.OnDelete(func(handle Handle[int], item *int) {
+		Append(&seen, *item)
+		Append(&errs, m.TryDelete(handle))
+	})
+	item, handle := m.Create()
+	*item = 42
+
+	TestExpect(t, m.TryDelete(handle) == nil, "first delete should succeed")
+	TestExpectf(t, SlicesEqual(seen, []int{42}), "callback should run once with the item's data, got %v", seen)
+	TestExpectf(t, len(errs) == 1 && errors.Is(errs[0], ErrHandleDeleted), "deleting from the callback should fail, got %v", errs)
+	TestExpect(t, m.Stats().Free == 1, "slot should be freed exactly once")
+}
+
+func TestChunkedManagerStablePointers(t *testing.T) {
+	m := MakeChunkedManager[int]()
+	first, firstHandle := m.Create()
+	*first = -1
+	ptrs := make(map[Handle[int]]*int)
+	// enough items to need several chunks
+	for i := range 3 * managerChunkSize {
+		item, handle := m.Create()
+		*item = i
+		ptrs[handle] = item
+	}
+	TestExpect(t, m.GetItem(firstHandle) == first && *first == -1, "the first item should not move when chunks are added")
+	for handle, ptr := range ptrs {
+		if !TestExpect(t, m.GetItem(handle) == ptr, "item pointers should stay valid as the manager grows") {
+			break
+		}
+	}
+
+	// deleting and creating reuses slots in place
+	m.Delete(firstHandle)
+	reused, _ := m.Create()
+	TestExpect(t, reused == first && *reused == 0, "a reused slot should be cleared and keep its address")
+}
+
+func TestManagerCompactAndStats(t *testing.T) {
+	for _, m := range []*Manager[int]{MakeManager[int](), MakeChunkedManager[int]()} {
+		var handles []Handle[int]
+		for i := range 2000 {
+			item, handle := m.Create()
+			*item = i
+			Append(&handles, handle)
+		}
+		for _, handle := range handles[10:] {
+			m.Delete(handle)
+		}
+		m.Delete(handles[3])
+		stats := m.Stats()
+		TestExpectf(t, stats.Live == 9 && stats.Free == 1991, "unexpected stats after deleting: %+v", stats)
+
+		m.Compact()
+		stats = m.Stats()
+		TestExpectf(t, stats.Live == 9 && stats.Free == 1, "Compact should only keep the free slots in the middle: %+v", stats)
+		TestExpectf(t, stats.Capacity < 2000, "Compact should release memory: %+v", stats)
+		for i, handle := range handles[:10] {
+			if i == 3 {
+				continue
+			}
+			item := m.GetItem(handle)
+			TestExpectf(t, item != nil && *item == i, "live handle %d should survive Compact", i)
+		}
+		TestExpect(t, !m.Valid(handles[500]), "trimmed handles should stay invalid")
+
+		_, handle := m.Create()
+		TestExpect(t, handle.slot == 3, "the remaining free slot should be reused first")
+		_, handle = m.Create()
+		TestExpect(t, handle.slot == 10, "new slots should start after the live ones")
+	}
+}
+
+func TestManagerResetInvalidatesHandles(t *testing.T) {
+	m := MakeManager[string]()
+	deleted := 0
+	m.OnDelete(func(handle Handle[string], item *string) {
+		deleted++
+	})
+	_, old := m.Create()
+	_, old2 := m.Create()
+	m.Reset()
+	TestExpect(t, deleted == 2, "Reset should call OnDelete for every live item")
+	TestExpect(t, m.Stats().Live == 0, "Reset should delete everything")
+
+	_, err := m.TryGet(old2)
+	TestExpectf(t, errors.Is(err, ErrHandleDeleted), "a handle from before Reset should be reported as deleted, got %v", err)
+
+	item, fresh := m.Create()
+	*item = "new"
+	TestExpect(t, fresh.slot == old.slot, "the slot should be reused after Reset")
+	TestExpect(t, !m.Valid(old) && m.GetItem(old) == nil, "old handle should not match the new item in its slot")
+	_, err = m.TryGet(old)
+	TestExpectf(t, errors.Is(err, ErrHandleDeleted), "old handle should be reported as deleted, got %v", err)
+	TestExpect(t, *m.GetItem(fresh) == "new", "the new handle should work")
+
+	_, err = m.TryGet(Handle[string]{slot: 0, gen: 1000})
+	TestExpectf(t, errors.Is(err, ErrInvalidHandle), "a never issued handle should be invalid, got %v", err)
+}
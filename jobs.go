@@ -87,14 +87,16 @@ func createChunkedQueue[T any](chunkSize int) *chunkedQueue[T] {
 }
 
 type JobQueue struct {
-	submitCh  chan Job
-	workersCh chan Job
+	submitCh    chan Job
+	workersCh   chan Job
+	workerCount int
 }
 
 func MakeJobQueue(workerCount int) *JobQueue {
 	jq := &JobQueue{
-		submitCh:  make(chan Job),
-		workersCh: make(chan Job),
+		submitCh:    make(chan Job),
+		workersCh:   make(chan Job),
+		workerCount: workerCount,
 	}
 
 	// launch workers goroutines
@@ -129,3 +131,7 @@ func MakeJobQueue(workerCount int) *JobQueue {
 func (jq *JobQueue) Submit(job Job) {
 	jq.submitCh <- job
 }
+
+func (jq *JobQueue) WorkerCount() int {
+	return jq.workerCount
+}
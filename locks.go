@@ -0,0 +1,182 @@
+package generic
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrLockTimeout = errors.New("timed out waiting for lock")
+
+// Locker is what the lock helpers need from a mutex. It's implemented by
+// *sync.Mutex, *sync.RWMutex, *TracedMutex and *TracedRWMutex.
+type Locker interface {
+	Lock()
+	Unlock()
+	TryLock() bool
+}
+
+// RWLocker is implemented by *sync.RWMutex and *TracedRWMutex
+type RWLocker interface {
+	Locker
+	RLock()
+	RUnlock()
+	TryRLock() bool
+}
+
+// Settings for locks built with the lockdebug tag; they have no effect
+// otherwise. See TracedMutex.
+var (
+	// how long to wait for a traced lock before reporting a suspected deadlock
+	LockDebugDeadlockTimeout = 10 * time.Second
+
+	// where lock debugging reports go; may be called from any goroutine
+	LockDebugReport = func(report string) {
+		LogWarningf("%s", report)
+	}
+)
+
+// LockStats are collected by traced locks in lockdebug builds
+type LockStats struct {
+	Acquisitions uint64
+	Contended    uint64 // acquisitions that had to wait
+	TotalWait    time.Duration
+	MaxWait      time.Duration
+}
+
+const (
+	lockBackoffMin = 10 * time.Microsecond
+	lockBackoffMax = 10 * time.Millisecond
+)
+
+// TryLockBackoff keeps calling tryLock (e.g. `mutex.TryLock`) until it
+// succeeds, sleeping between attempts with exponential backoff. Returns the
+// context's error if it's done before the lock is acquired.
+//
+// Polling never registers the caller as waiting for the lock, so it can
+// starve: a writer polling an RWMutex with TryLock may never get in while
+// readers keep coming and going, because they never see a writer waiting. The
+// Ctx and Timeout lock helpers block in Lock instead and don't have this
+// problem; use this only for locks that have nothing but a TryLock.
+func TryLockBackoff(ctx context.Context, tryLock func() bool) error {
+	delay := lockBackoffMin
+	var timer *time.Timer
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if tryLock() {
+			return nil
+		}
+		if timer == nil {
+			timer = time.NewTimer(delay)
+			defer timer.Stop()
+		} else {
+			timer.Reset(delay)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+		delay = min(delay*2, lockBackoffMax)
+	}
+}
+
+// lockCtx waits for the lock like lock() does, so the caller is queued fairly
+// with the other waiters, but stops waiting when the context is done. The
+// sync package locks can't be waited on with a context, so the waiting
+// happens in a goroutine; if the context is done first, that goroutine
+// releases the lock as soon as it gets it. Traced locks record the caller as
+// the one holding the lock, not the helper goroutine.
+func lockCtx(ctx context.Context, tryLock func() bool, lock func(), unlock func()) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if tryLock() {
+		return nil
+	}
+	handoff := make(chan struct{})
+	owner := lockOwner()
+	go func() {
+		runAsLockOwner(owner, lock)
+		select {
+		case handoff <- struct{}{}:
+		case <-ctx.Done():
+			runAsLockOwner(owner, unlock)
+		}
+	}()
+	select {
+	case <-handoff:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func withTimeout(timeout time.Duration, fn func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	err := fn(ctx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrLockTimeout
+	}
+	return err
+}
+
+// WithLockCtx is like WithLock but gives up waiting for the lock when the
+// context is done, returning its error
+func WithLockCtx(ctx context.Context, lock Locker, fn func()) error {
+	if err := lockCtx(ctx, lock.TryLock, lock.Lock, lock.Unlock); err != nil {
+		return err
+	}
+	defer lock.Unlock()
+	fn()
+	return nil
+}
+
+// WithWriteLockCtx is like WithWriteLock but gives up waiting for the lock
+// when the context is done, returning its error
+func WithWriteLockCtx(ctx context.Context, lock RWLocker, fn func()) error {
+	if err := lockCtx(ctx, lock.TryLock, lock.Lock, lock.Unlock); err != nil {
+		return err
+	}
+	defer lock.Unlock()
+	fn()
+	return nil
+}
+
+// WithReadLockCtx is like WithReadLock but gives up waiting for the lock when
+// the context is done, returning its error
+func WithReadLockCtx(ctx context.Context, lock RWLocker, fn func()) error {
+	if err := lockCtx(ctx, lock.TryRLock, lock.RLock, lock.RUnlock); err != nil {
+		return err
+	}
+	defer lock.RUnlock()
+	fn()
+	return nil
+}
+
+// WithLockTimeout is like WithLock but returns ErrLockTimeout if the lock
+// can't be acquired within the timeout
+func WithLockTimeout(lock Locker, timeout time.Duration, fn func()) error {
+	return withTimeout(timeout, func(ctx context.Context) error {
+		return WithLockCtx(ctx, lock, fn)
+	})
+}
+
+// WithWriteLockTimeout is like WithWriteLock but returns ErrLockTimeout if the
+// lock can't be acquired within the timeout
+func WithWriteLockTimeout(lock RWLocker, timeout time.Duration, fn func()) error {
+	return withTimeout(timeout, func(ctx context.Context) error {
+		return WithWriteLockCtx(ctx, lock, fn)
+	})
+}
+
+// WithReadLockTimeout is like WithReadLock but returns ErrLockTimeout if the
+// lock can't be acquired within the timeout
+func WithReadLockTimeout(lock RWLocker, timeout time.Duration, fn func()) error {
+	return withTimeout(timeout, func(ctx context.Context) error {
+		return WithReadLockCtx(ctx, lock, fn)
+	})
+}
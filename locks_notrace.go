@@ -0,0 +1,48 @@
+//go:build !lockdebug
+
+package generic
+
+import (
+	"sync"
+)
+
+// TracedMutex is a named sync.Mutex. When built with the lockdebug tag, it
+// records who holds it, how long goroutines wait for it, lock ordering
+// violations against other named locks, and reports suspected deadlocks
+// (see LockDebugDeadlockTimeout). In normal builds it's just a sync.Mutex.
+type TracedMutex struct {
+	sync.Mutex
+}
+
+func NewTracedMutex(name string) *TracedMutex {
+	return new(TracedMutex)
+}
+
+// Stats always returns zero stats in normal builds
+func (m *TracedMutex) Stats() LockStats {
+	return LockStats{}
+}
+
+// TracedRWMutex is the RWMutex version of TracedMutex
+type TracedRWMutex struct {
+	sync.RWMutex
+}
+
+func NewTracedRWMutex(name string) *TracedRWMutex {
+	return new(TracedRWMutex)
+}
+
+// Stats always returns zero stats in normal builds
+func (m *TracedRWMutex) Stats() LockStats {
+	return LockStats{}
+}
+
+// lockOwner and runAsLockOwner let lockCtx take a lock on a helper goroutine
+// on behalf of the caller; they only matter to traced locks in lockdebug builds
+func lockOwner() int64 {
+	return 0
+}
+
+func runAsLockOwner(owner int64, fn func()) {
+	fn()
+}
@@ -0,0 +1,79 @@
+package generic
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWriteLockWithReaderTraffic(t *testing.T) {
+	var rw sync.RWMutex
+	var stop atomic.Bool
+	var wg sync.WaitGroup
+	// overlapping readers, so there's never a moment with no reader holding it
+	for range 8 {
+		WaitGroupGo(&wg, func() {
+			for !stop.Load() {
+				rw.RLock()
+				time.Sleep(time.Millisecond)
+				rw.RUnlock()
+			}
+		})
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	ran := false
+	err := WithWriteLockTimeout(&rw, 5*time.Second, func() {
+		ran = true
+	})
+	stop.Store(true)
+	wg.Wait()
+	TestExpectf(t, err == nil && ran, "writer should get the lock despite the readers: %v", err)
+}
+
+func TestLockTimeout(t *testing.T) {
+	var mu sync.Mutex
+	mu.Lock()
+	ran := false
+	err := WithLockTimeout(&mu, 20*time.Millisecond, func() {
+		ran = true
+	})
+	TestExpectf(t, errors.Is(err, ErrLockTimeout), "expected a timeout, got %v", err)
+	TestExpect(t, !ran, "fn should not run on timeout")
+	mu.Unlock()
+
+	// the abandoned wait must not leave the lock held
+	err = WithLockTimeout(&mu, time.Second, func() {
+		ran = true
+	})
+	TestExpectf(t, err == nil && ran, "lock should be usable after a timeout: %v", err)
+	TestExpect(t, mu.TryLock(), "lock should be free afterwards")
+	mu.Unlock()
+
+	var rw sync.RWMutex
+	rw.Lock()
+	err = WithReadLockTimeout(&rw, 20*time.Millisecond, func() {})
+	TestExpectf(t, errors.Is(err, ErrLockTimeout), "read lock should time out while written: %v", err)
+	rw.Unlock()
+	err = WithReadLockTimeout(&rw, time.Second, func() {})
+	TestExpectf(t, err == nil, "read lock should be usable after a timeout: %v", err)
+	err = WithWriteLockTimeout(&rw, time.Second, func() {})
+	TestExpectf(t, err == nil, "no reader should be left behind: %v", err)
+}
+
+func TestLockCtxCanceled(t *testing.T) {
+	var mu sync.Mutex
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	ran := false
+	err := WithLockCtx(ctx, &mu, func() {
+		ran = true
+	})
+	TestExpectf(t, errors.Is(err, context.Canceled), "done context should fail right away: %v", err)
+	TestExpect(t, !ran, "fn should not run with a done context")
+	TestExpect(t, mu.TryLock(), "lock should not be held")
+	mu.Unlock()
+}
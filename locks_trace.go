@@ -0,0 +1,299 @@
+//go:build lockdebug
+
+package generic
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// goroutineID parses the current goroutine's id out of its stack header. It's
+// slow, which is fine for debug builds only.
+func goroutineID() int64 {
+	var buf [64]byte
+	header := buf[:runtime.Stack(buf[:], false)]
+	header = bytes.TrimPrefix(header, []byte("goroutine "))
+	if idx := bytes.IndexByte(header, ' '); idx != -1 {
+		header = header[:idx]
+	}
+	id, _ := strconv.ParseInt(string(header), 10, 64)
+	return id
+}
+
+// lockDebug tracks which traced locks each goroutine holds and the order in
+// which pairs of named locks have been acquired
+var lockDebug struct {
+	lock     sync.Mutex
+	held     map[int64][]*lockTrace
+	order    map[[2]string][]byte // (first, second) -> stack where that order was first seen
+	reported map[[2]string]bool
+	proxies  map[int64]int64 // goroutine -> goroutine it's locking on behalf of
+}
+
+// lockOwner returns the goroutine id that traced locks record as holder for
+// the current goroutine: its own, unless it's running runAsLockOwner
+func lockOwner() int64 {
+	gid := goroutineID()
+	lockDebug.lock.Lock()
+	defer lockDebug.lock.Unlock()
+	if owner, found := lockDebug.proxies[gid]; found {
+		return owner
+	}
+	return gid
+}
+
+// runAsLockOwner runs fn with traced locks treating it as running on the
+// owner goroutine, for locks taken on a helper goroutine by lockCtx
+func runAsLockOwner(owner int64, fn func()) {
+	gid := goroutineID()
+	lockDebug.lock.Lock()
+	EnsureMapNotNil(&lockDebug.proxies)
+	lockDebug.proxies[gid] = owner
+	lockDebug.lock.Unlock()
+	defer func() {
+		lockDebug.lock.Lock()
+		delete(lockDebug.proxies, gid)
+		lockDebug.lock.Unlock()
+	}()
+	fn()
+}
+
+type lockTrace struct {
+	name string
+
+	lock        sync.Mutex // protects the fields below
+	writer      int64      // goroutine holding the write lock, 0 if none
+	writerStack []byte
+	readers     map[int64][][]byte // goroutine -> stack of each read lock it holds
+	stats       LockStats
+}
+
+func (t *lockTrace) displayName() string {
+	if t.name == "" {
+		return "(unnamed)"
+	}
+	return t.name
+}
+
+// checkOrder reports if acquiring t while holding other named locks inverts
+// an order seen before
+func (t *lockTrace) checkOrder(gid int64) {
+	if t.name == "" {
+		return
+	}
+	lockDebug.lock.Lock()
+	defer lockDebug.lock.Unlock()
+	for _, held := range lockDebug.held[gid] {
+		if held.name == "" || held.name == t.name {
+			continue
+		}
+		pair := [2]string{held.name, t.name}
+		if otherStack, found := lockDebug.order[[2]string{t.name, held.name}]; found && !lockDebug.reported[pair] {
+			EnsureMapNotNil(&lockDebug.reported)
+			lockDebug.reported[pair] = true
+			LockDebugReport(fmt.Sprintf(
+				"lock order violation: acquiring %q while holding %q, but elsewhere %q was acquired while holding %q\nthis acquisition:\n%s\nthe other order was seen at:\n%s",
+				t.name, held.name, held.name, t.name, debug.Stack(), otherStack))
+		}
+		if _, found := lockDebug.order[pair]; !found {
+			EnsureMapNotNil(&lockDebug.order)
+			lockDebug.order[pair] = debug.Stack()
+		}
+	}
+}
+
+func (t *lockTrace) holders() string {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	var out strings.Builder
+	if t.writer != 0 {
+		fmt.Fprintf(&out, "held by goroutine %d, acquired at:\n%s\n", t.writer, t.writerStack)
+	}
+	for gid, stacks := range t.readers {
+		for _, stack := range stacks {
+			fmt.Fprintf(&out, "read locked by goroutine %d, acquired at:\n%s\n", gid, stack)
+		}
+	}
+	return out.String()
+}
+
+// acquire runs the actual locking, timing the wait and reporting a suspected
+// deadlock if it takes too long
+func (t *lockTrace) acquire(tryLock func() bool, lock func(), read bool) {
+	gid := lockOwner()
+	t.checkOrder(gid)
+
+	start := time.Now()
+	contended := !tryLock()
+	if contended {
+		// the report runs on the timer's goroutine, so take the waiter's stack now
+		waitStack := debug.Stack()
+		watchdog := time.AfterFunc(LockDebugDeadlockTimeout, func() {
+			LockDebugReport(fmt.Sprintf(
+				"suspected deadlock: goroutine %d has been waiting %v for lock %s\nwaiting at:\n%s\n%s",
+				gid, time.Since(start), t.displayName(), waitStack, t.holders()))
+		})
+		lock()
+		watchdog.Stop()
+	}
+	t.acquired(gid, read, contended, time.Since(start))
+}
+
+// tryAcquire is acquire for TryLock; it can't deadlock so there's nothing to
+// check or time
+func (t *lockTrace) tryAcquire(tryLock func() bool, read bool) bool {
+	if !tryLock() {
+		return false
+	}
+	t.acquired(lockOwner(), read, false, 0)
+	return true
+}
+
+func (t *lockTrace) acquired(gid int64, read bool, contended bool, wait time.Duration) {
+	stack := debug.Stack()
+	t.lock.Lock()
+	if read {
+		EnsureMapNotNil(&t.readers)
+		t.readers[gid] = append(t.readers[gid], stack)
+	} else {
+		t.writer = gid
+		t.writerStack = stack
+	}
+	t.stats.Acquisitions++
+	if contended {
+		t.stats.Contended++
+		t.stats.TotalWait += wait
+		t.stats.MaxWait = max(t.stats.MaxWait, wait)
+	}
+	t.lock.Unlock()
+
+	lockDebug.lock.Lock()
+	defer lockDebug.lock.Unlock()
+	EnsureMapNotNil(&lockDebug.held)
+	held := lockDebug.held[gid]
+	Append(&held, t)
+	lockDebug.held[gid] = held
+}
+
+// release must be called before the actual unlocking
+func (t *lockTrace) release(read bool) {
+	var gid int64
+	t.lock.Lock()
+	if read {
+		// read locks can be nested, so only drop the latest one
+		gid = lockOwner()
+		if stacks := t.readers[gid]; len(stacks) > 1 {
+			t.readers[gid] = stacks[:len(stacks)-1]
+		} else {
+			delete(t.readers, gid)
+		}
+	} else {
+		// a mutex may be unlocked by a different goroutine than the one that
+		// locked it
+		gid = t.writer
+		t.writer = 0
+		t.writerStack = nil
+	}
+	t.lock.Unlock()
+
+	lockDebug.lock.Lock()
+	defer lockDebug.lock.Unlock()
+	held := lockDebug.held[gid]
+	for idx := len(held) - 1; idx >= 0; idx-- {
+		if held[idx] == t {
+			RemoveAt(&held, idx, 1)
+			break
+		}
+	}
+	if len(held) == 0 {
+		delete(lockDebug.held, gid)
+	} else {
+		lockDebug.held[gid] = held
+	}
+}
+
+func (t *lockTrace) getStats() LockStats {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.stats
+}
+
+// TracedMutex is a named sync.Mutex. When built with the lockdebug tag, it
+// records who holds it, how long goroutines wait for it, lock ordering
+// violations against other named locks, and reports suspected deadlocks
+// (see LockDebugDeadlockTimeout). In normal builds it's just a sync.Mutex.
+type TracedMutex struct {
+	sync.Mutex
+	trace lockTrace
+}
+
+func NewTracedMutex(name string) *TracedMutex {
+	m := new(TracedMutex)
+	m.trace.name = name
+	return m
+}
+
+func (m *TracedMutex) Lock() {
+	m.trace.acquire(m.Mutex.TryLock, m.Mutex.Lock, false)
+}
+
+func (m *TracedMutex) TryLock() bool {
+	return m.trace.tryAcquire(m.Mutex.TryLock, false)
+}
+
+func (m *TracedMutex) Unlock() {
+	m.trace.release(false)
+	m.Mutex.Unlock()
+}
+
+func (m *TracedMutex) Stats() LockStats {
+	return m.trace.getStats()
+}
+
+// TracedRWMutex is the RWMutex version of TracedMutex
+type TracedRWMutex struct {
+	sync.RWMutex
+	trace lockTrace
+}
+
+func NewTracedRWMutex(name string) *TracedRWMutex {
+	m := new(TracedRWMutex)
+	m.trace.name = name
+	return m
+}
+
+func (m *TracedRWMutex) Lock() {
+	m.trace.acquire(m.RWMutex.TryLock, m.RWMutex.Lock, false)
+}
+
+func (m *TracedRWMutex) TryLock() bool {
+	return m.trace.tryAcquire(m.RWMutex.TryLock, false)
+}
+
+func (m *TracedRWMutex) Unlock() {
+	m.trace.release(false)
+	m.RWMutex.Unlock()
+}
+
+func (m *TracedRWMutex) RLock() {
+	m.trace.acquire(m.RWMutex.TryRLock, m.RWMutex.RLock, true)
+}
+
+func (m *TracedRWMutex) TryRLock() bool {
+	return m.trace.tryAcquire(m.RWMutex.TryRLock, true)
+}
+
+func (m *TracedRWMutex) RUnlock() {
+	m.trace.release(true)
+	m.RWMutex.RUnlock()
+}
+
+func (m *TracedRWMutex) Stats() LockStats {
+	return m.trace.getStats()
+}
@@ -0,0 +1,148 @@
+//go:build lockdebug
+
+package generic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// collectLockReports redirects LockDebugReport for the duration of the test.
+// Reports can come from timer goroutines, hence the lock.
+func collectLockReports(t *testing.T) func() []string {
+	var lock sync.Mutex
+	var reports []string
+	prevReport, prevTimeout := LockDebugReport, LockDebugDeadlockTimeout
+	LockDebugReport = func(report string) {
+		lock.Lock()
+		defer lock.Unlock()
+		Append(&reports, report)
+	}
+	t.Cleanup(func() {
+		LockDebugReport, LockDebugDeadlockTimeout = prevReport, prevTimeout
+	})
+	return func() []string {
+		lock.Lock()
+		defer lock.Unlock()
+		return Clone(reports)
+	}
+}
+
+func TestTracedMutexDeadlockReport(t *testing.T) {
+	reports := collectLockReports(t)
+	LockDebugDeadlockTimeout = 20 * time.Millisecond
+
+	m := NewTracedMutex("deadlock-test")
+	m.Lock()
+	done := make(chan struct{})
+	go func() {
+		m.Lock()
+		m.Unlock()
+		close(done)
+	}()
+	time.Sleep(100 * time.Millisecond)
+	m.Unlock()
+	<-done
+
+	got := reports()
+	if !TestExpectf(t, len(got) == 1, "expected one deadlock report, got %d", len(got)) {
+		return
+	}
+	report := got[0]
+	TestExpectf(t, strings.Contains(report, "suspected deadlock") && strings.Contains(report, "deadlock-test"), "unexpected report:\n%s", report)
+	waitingAt := report[strings.Index(report, "waiting at:"):strings.Index(report, "held by goroutine")]
+	TestExpectf(t, strings.Contains(waitingAt, "TestTracedMutexDeadlockReport"), "report should show where the waiter is stuck:\n%s", waitingAt)
+	TestExpectf(t, strings.Contains(report, "held by goroutine"), "report should show the holder:\n%s", report)
+
+	stats := m.Stats()
+	TestExpectf(t, stats.Acquisitions == 2 && stats.Contended == 1, "unexpected stats: %+v", stats)
+	TestExpectf(t, stats.MaxWait >= 100*time.Millisecond && stats.TotalWait == stats.MaxWait, "unexpected wait times: %+v", stats)
+}
+
+var orderTestRuns atomic.Int32
+
+func TestTracedMutexOrderViolation(t *testing.T) {
+	reports := collectLockReports(t)
+	// the order seen is global, so use new names each time the test runs
+	run := orderTestRuns.Add(1)
+	a := NewTracedMutex(fmt.Sprintf("order-test-a%d", run))
+	b := NewTracedRWMutex(fmt.Sprintf("order-test-b%d", run))
+
+	a.Lock()
+	b.RLock()
+	b.RUnlock()
+	a.Unlock()
+	TestExpect(t, len(reports()) == 0, "a consistent order should not be reported")
+
+	b.Lock()
+	a.Lock()
+	a.Unlock()
+	b.Unlock()
+	got := reports()
+	if !TestExpectf(t, len(got) == 1, "expected one order report, got %d", len(got)) {
+		return
+	}
+	TestExpectf(t, strings.Contains(got[0], fmt.Sprintf(`acquiring %q while holding %q`, a.trace.name, b.trace.name)), "unexpected report:\n%s", got[0])
+
+	b.Lock()
+	a.Lock()
+	a.Unlock()
+	b.Unlock()
+	TestExpect(t, len(reports()) == 1, "the same violation should only be reported once")
+}
+
+// heldCount returns how many times the lock appears in the held lists
+func heldCount(t *lockTrace) int {
+	lockDebug.lock.Lock()
+	defer lockDebug.lock.Unlock()
+	count := 0
+	for _, held := range lockDebug.held {
+		for _, h := range held {
+			if h == t {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+func TestTracedRWMutexReadLockCtx(t *testing.T) {
+	m := NewTracedRWMutex("ctx-read-test")
+	// hold the write lock for a bit so the read lock is taken on lockCtx's helper
+	m.Lock()
+	time.AfterFunc(20*time.Millisecond, m.Unlock)
+	caller := goroutineID()
+	var inside string
+	err := WithReadLockCtx(context.Background(), m, func() {
+		inside = m.trace.holders()
+	})
+	TestExpectf(t, err == nil, "read lock should be acquired: %v", err)
+	TestExpectf(t, strings.Contains(inside, fmt.Sprintf("read locked by goroutine %d,", caller)), "the caller should be recorded as the reader:\n%s", inside)
+	TestExpectf(t, m.trace.holders() == "", "no reader should be left behind:\n%s", m.trace.holders())
+	TestExpect(t, heldCount(&m.trace) == 0, "the lock should not be left in any held list")
+
+	// a timed out wait releases the lock on the helper goroutine
+	m.Lock()
+	err = WithReadLockTimeout(m, 20*time.Millisecond, func() {})
+	TestExpectf(t, errors.Is(err, ErrLockTimeout), "expected a timeout, got %v", err)
+	m.Unlock()
+	TestExpect(t, WithWriteLockTimeout(m, time.Second, func() {}) == nil, "the abandoned read lock should be released")
+	TestExpectf(t, m.trace.holders() == "" && heldCount(&m.trace) == 0, "the abandoned read lock should not be left behind:\n%s", m.trace.holders())
+}
+
+func TestTracedRWMutexNestedReadLocks(t *testing.T) {
+	m := NewTracedRWMutex("nested-read-test")
+	m.RLock()
+	m.RLock()
+	m.RUnlock()
+	TestExpect(t, strings.Count(m.trace.holders(), "read locked by") == 1, "one read lock should still be recorded")
+	TestExpect(t, heldCount(&m.trace) == 1, "one read lock should still be held")
+	m.RUnlock()
+	TestExpect(t, m.trace.holders() == "" && heldCount(&m.trace) == 0, "both read locks should be released")
+}
@@ -0,0 +1,384 @@
+package generic
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"reflect"
+	"strconv"
+)
+
+type orderedEntry[K comparable, V any] struct {
+	key   K
+	value V
+	prev  *orderedEntry[K, V]
+	next  *orderedEntry[K, V]
+}
+
+// OrderedMap is a map that remembers the order in which keys were inserted.
+// Lookups, insertions, deletions and moves are all O(1). The zero value is an
+// empty map ready to use. Not safe for concurrent use.
+type OrderedMap[K comparable, V any] struct {
+	entries map[K]*orderedEntry[K, V]
+	head    *orderedEntry[K, V]
+	tail    *orderedEntry[K, V]
+}
+
+func NewOrderedMap[K comparable, V any]() *OrderedMap[K, V] {
+	return &OrderedMap[K, V]{
+		entries: make(map[K]*orderedEntry[K, V]),
+	}
+}
+
+func (m *OrderedMap[K, V]) unlink(e *orderedEntry[K, V]) {
+	if e.prev != nil {
+		e.prev.next = e.next
+	} else {
+		m.head = e.next
+	}
+	if e.next != nil {
+		e.next.prev = e.prev
+	} else {
+		m.tail = e.prev
+	}
+	e.prev = nil
+	e.next = nil
+}
+
+func (m *OrderedMap[K, V]) linkBack(e *orderedEntry[K, V]) {
+	e.prev = m.tail
+	if m.tail != nil {
+		m.tail.next = e
+	} else {
+		m.head = e
+	}
+	m.tail = e
+}
+
+func (m *OrderedMap[K, V]) linkFront(e *orderedEntry[K, V]) {
+	e.next = m.head
+	if m.head != nil {
+		m.head.prev = e
+	} else {
+		m.tail = e
+	}
+	m.head = e
+}
+
+// Set sets the value for the key. New keys are added at the back; existing
+// keys keep their position.
+func (m *OrderedMap[K, V]) Set(key K, value V) {
+	if e, found := m.entries[key]; found {
+		e.value = value
+		return
+	}
+	EnsureMapNotNil(&m.entries)
+	e := &orderedEntry[K, V]{key: key, value: value}
+	m.entries[key] = e
+	m.linkBack(e)
+}
+
+func (m *OrderedMap[K, V]) Get(key K) (V, bool) {
+	if e, found := m.entries[key]; found {
+		return e.value, true
+	}
+	var zero V
+	return zero, false
+}
+
+func (m *OrderedMap[K, V]) Has(key K) bool {
+	_, found := m.entries[key]
+	return found
+}
+
+// Delete removes the key and reports whether it was present
+func (m *OrderedMap[K, V]) Delete(key K) bool {
+	e, found := m.entries[key]
+	if !found {
+		return false
+	}
+	m.unlink(e)
+	delete(m.entries, key)
+	return true
+}
+
+func (m *OrderedMap[K, V]) Len() int {
+	return len(m.entries)
+}
+
+func (m *OrderedMap[K, V]) Clear() {
+	clear(m.entries)
+	m.head = nil
+	m.tail = nil
+}
+
+// MoveToFront moves the key to the front of the order. Returns false if the
+// key is not in the map.
+func (m *OrderedMap[K, V]) MoveToFront(key K) bool {
+	e, found := m.entries[key]
+	if found && e != m.head {
+		m.unlink(e)
+		m.linkFront(e)
+	}
+	return found
+}
+
+// MoveToBack moves the key to the back of the order. Returns false if the key
+// is not in the map.
+func (m *OrderedMap[K, V]) MoveToBack(key K) bool {
+	e, found := m.entries[key]
+	if found && e != m.tail {
+		m.unlink(e)
+		m.linkBack(e)
+	}
+	return found
+}
+
+// Front returns the first key and value in the order
+func (m *OrderedMap[K, V]) Front() (key K, value V, found bool) {
+	if m.head == nil {
+		return
+	}
+	return m.head.key, m.head.value, true
+}
+
+// Back returns the last key and value in the order
+func (m *OrderedMap[K, V]) Back() (key K, value V, found bool) {
+	if m.tail == nil {
+		return
+	}
+	return m.tail.key, m.tail.value, true
+}
+
+// All iterates over the keys and values in order. It's fine to delete the
+// current key while iterating.
+func (m *OrderedMap[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for e := m.head; e != nil; {
+			next := e.next
+			if !yield(e.key, e.value) {
+				return
+			}
+			e = next
+		}
+	}
+}
+
+// Backward iterates over the keys and values in reverse order
+func (m *OrderedMap[K, V]) Backward() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for e := m.tail; e != nil; {
+			prev := e.prev
+			if !yield(e.key, e.value) {
+				return
+			}
+			e = prev
+		}
+	}
+}
+
+func (m *OrderedMap[K, V]) Keys() []K {
+	keys := make([]K, 0, m.Len())
+	for e := m.head; e != nil; e = e.next {
+		Append(&keys, e.key)
+	}
+	return keys
+}
+
+func (m *OrderedMap[K, V]) Values() []V {
+	values := make([]V, 0, m.Len())
+	for e := m.head; e != nil; e = e.next {
+		Append(&values, e.value)
+	}
+	return values
+}
+
+// encodeMapKey follows the rules encoding/json uses for map keys
+func encodeMapKey[K comparable](key K) (string, error) {
+	rv := reflect.ValueOf(&key).Elem()
+	if rv.Kind() == reflect.String {
+		return rv.String(), nil
+	}
+	if tm, ok := any(key).(encoding.TextMarshaler); ok {
+		text, err := tm.MarshalText()
+		return string(text), err
+	}
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(rv.Uint(), 10), nil
+	}
+	return "", fmt.Errorf("unsupported map key type: %v", rv.Type())
+}
+
+func decodeMapKey[K comparable](text string) (key K, err error) {
+	// like encoding/json, UnmarshalText wins over the key being a string
+	if tu, ok := any(&key).(encoding.TextUnmarshaler); ok {
+		err = tu.UnmarshalText([]byte(text))
+		return
+	}
+	rv := reflect.ValueOf(&key).Elem()
+	if rv.Kind() == reflect.String {
+		rv.SetString(text)
+		return
+	}
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var n int64
+		n, err = strconv.ParseInt(text, 10, rv.Type().Bits())
+		rv.SetInt(n)
+		return
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		var n uint64
+		n, err = strconv.ParseUint(text, 10, rv.Type().Bits())
+		rv.SetUint(n)
+		return
+	}
+	err = fmt.Errorf("unsupported map key type: %v", rv.Type())
+	return
+}
+
+// MarshalJSON encodes the map as a JSON object with the keys in order
+func (m OrderedMap[K, V]) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for e := m.head; e != nil; e = e.next {
+		if e != m.head {
+			buf.WriteByte(',')
+		}
+		keyText, err := encodeMapKey(e.key)
+		if err != nil {
+			return nil, err
+		}
+		keyData, err := json.Marshal(keyText)
+		if err != nil {
+			return nil, err
+		}
+		valueData, err := json.Marshal(e.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(keyData)
+		buf.WriteByte(':')
+		buf.Write(valueData)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON decodes a JSON object, replacing the contents of the map and
+// keeping the order of the keys as they appear in the object
+func (m *OrderedMap[K, V]) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok == nil { // null
+		return nil
+	}
+	if tok != json.Delim('{') {
+		return fmt.Errorf("expected JSON object, got %v", tok)
+	}
+	m.Clear()
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key, err := decodeMapKey[K](tok.(string))
+		if err != nil {
+			return err
+		}
+		var value V
+		if err := dec.Decode(&value); err != nil {
+			return err
+		}
+		m.Set(key, value)
+	}
+	_, err = dec.Token() // closing brace
+	return err
+}
+
+// OrderedSet is a set that remembers the order in which items were added. The
+// zero value is an empty set ready to use. Not safe for concurrent use.
+type OrderedSet[T comparable] struct {
+	m OrderedMap[T, struct{}]
+}
+
+func NewOrderedSetFrom[T comparable](items []T) *OrderedSet[T] {
+	s := new(OrderedSet[T])
+	s.Add(items...)
+	return s
+}
+
+// Add adds the items that are not already in the set at the back
+func (s *OrderedSet[T]) Add(items ...T) {
+	for _, item := range items {
+		s.m.Set(item, struct{}{})
+	}
+}
+
+func (s *OrderedSet[T]) Has(item T) bool {
+	return s.m.Has(item)
+}
+
+// Remove removes the item and reports whether it was present
+func (s *OrderedSet[T]) Remove(item T) bool {
+	return s.m.Delete(item)
+}
+
+func (s *OrderedSet[T]) Len() int {
+	return s.m.Len()
+}
+
+func (s *OrderedSet[T]) Clear() {
+	s.m.Clear()
+}
+
+func (s *OrderedSet[T]) MoveToFront(item T) bool {
+	return s.m.MoveToFront(item)
+}
+
+func (s *OrderedSet[T]) MoveToBack(item T) bool {
+	return s.m.MoveToBack(item)
+}
+
+// All iterates over the items in order
+func (s *OrderedSet[T]) All() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for item := range s.m.All() {
+			if !yield(item) {
+				return
+			}
+		}
+	}
+}
+
+// List returns the items in order
+func (s *OrderedSet[T]) List() []T {
+	return s.m.Keys()
+}
+
+// MarshalJSON encodes the set as a JSON array in order
+func (s OrderedSet[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.m.Keys())
+}
+
+// UnmarshalJSON decodes a JSON array, replacing the contents of the set. Like
+// for OrderedMap, null leaves the set as it is.
+func (s *OrderedSet[T]) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	var items []T
+	if err := json.Unmarshal(data, &items); err != nil {
+		return err
+	}
+	s.Clear()
+	s.Add(items...)
+	return nil
+}
@@ -0,0 +1,121 @@
+package generic
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// a key type that only encodes through MarshalText
+type gridKey struct {
+	X, Y int
+}
+
+func (k gridKey) MarshalText() ([]byte, error) {
+	return fmt.Appendf(nil, "%d,%d", k.X, k.Y), nil
+}
+
+func (k *gridKey) UnmarshalText(text []byte) error {
+	_, err := fmt.Sscanf(string(text), "%d,%d", &k.X, &k.Y)
+	return err
+}
+
+// a string key whose UnmarshalText validates its input
+type lowerKey string
+
+func (k *lowerKey) UnmarshalText(text []byte) error {
+	if strings.ToLower(string(text)) != string(text) {
+		return fmt.Errorf("key %q is not lower case", text)
+	}
+	*k = lowerKey(text)
+	return nil
+}
+
+func TestOrderedMapJSON(t *testing.T) {
+	var m OrderedMap[string, int]
+	m.Set("zebra", 1)
+	m.Set("apple", 2)
+	m.Set("mango", 3)
+	data, err := json.Marshal(m)
+	TestExpect(t, err == nil, "marshal should succeed")
+	TestExpectf(t, string(data) == `{"zebra":1,"apple":2,"mango":3}`, "keys should keep their order: %s", data)
+
+	var decoded OrderedMap[string, int]
+	TestExpect(t, json.Unmarshal([]byte(`{"b":1,"c":2,"a":3}`), &decoded) == nil, "unmarshal should succeed")
+	TestExpectf(t, SlicesEqual(decoded.Keys(), []string{"b", "c", "a"}), "keys should be in input order: %v", decoded.Keys())
+	TestExpectf(t, SlicesEqual(decoded.Values(), []int{1, 2, 3}), "unexpected values: %v", decoded.Values())
+
+	TestExpect(t, json.Unmarshal([]byte(`null`), &decoded) == nil, "null should be accepted")
+	TestExpect(t, decoded.Len() == 3, "null should leave the map unchanged")
+
+	var empty OrderedMap[string, int]
+	TestExpect(t, json.Unmarshal([]byte(`null`), &empty) == nil && empty.Len() == 0, "null should leave an empty map empty")
+
+	TestExpect(t, json.Unmarshal([]byte(`[1,2]`), &decoded) != nil, "non-object should fail")
+	TestExpect(t, json.Unmarshal([]byte(`{"a":"x"}`), &decoded) != nil, "bad value should fail")
+}
+
+func TestOrderedMapJSONKeys(t *testing.T) {
+	var ints OrderedMap[int, string]
+	ints.Set(10, "ten")
+	ints.Set(-2, "minus two")
+	data, _ := json.Marshal(ints)
+	TestExpectf(t, string(data) == `{"10":"ten","-2":"minus two"}`, "int keys should be quoted: %s", data)
+	var decodedInts OrderedMap[int, string]
+	TestExpect(t, json.Unmarshal(data, &decodedInts) == nil, "int keys should decode")
+	TestExpectf(t, SlicesEqual(decodedInts.Keys(), []int{10, -2}), "unexpected keys: %v", decodedInts.Keys())
+	TestExpect(t, json.Unmarshal([]byte(`{"x":"y"}`), &decodedInts) != nil, "non-numeric int key should fail")
+
+	var grid OrderedMap[gridKey, bool]
+	grid.Set(gridKey{1, 2}, true)
+	grid.Set(gridKey{0, 0}, false)
+	data, _ = json.Marshal(grid)
+	TestExpectf(t, string(data) == `{"1,2":true,"0,0":false}`, "keys should use MarshalText: %s", data)
+	var decodedGrid OrderedMap[gridKey, bool]
+	TestExpect(t, json.Unmarshal(data, &decodedGrid) == nil, "keys should decode with UnmarshalText")
+	TestExpectf(t, SlicesEqual(decodedGrid.Keys(), []gridKey{{1, 2}, {0, 0}}), "unexpected keys: %v", decodedGrid.Keys())
+
+	var lower OrderedMap[lowerKey, int]
+	TestExpect(t, json.Unmarshal([]byte(`{"ok":1}`), &lower) == nil, "valid key should decode")
+	TestExpect(t, json.Unmarshal([]byte(`{"NOPE":1}`), &lower) != nil, "UnmarshalText should be used even for string keys")
+
+	// same as encoding/json
+	var std map[lowerKey]int
+	TestExpect(t, json.Unmarshal([]byte(`{"NOPE":1}`), &std) != nil, "encoding/json also uses UnmarshalText for string keys")
+}
+
+func TestOrderedSetJSON(t *testing.T) {
+	s := NewOrderedSetFrom([]string{"c", "a", "b"})
+	data, err := json.Marshal(s)
+	TestExpect(t, err == nil, "marshal should succeed")
+	TestExpectf(t, string(data) == `["c","a","b"]`, "items should keep their order: %s", data)
+
+	var decoded OrderedSet[string]
+	TestExpect(t, json.Unmarshal([]byte(`["y","x","y"]`), &decoded) == nil, "unmarshal should succeed")
+	TestExpectf(t, SlicesEqual(decoded.List(), []string{"y", "x"}), "duplicates should be dropped: %v", decoded.List())
+	TestExpect(t, json.Unmarshal([]byte(`null`), &decoded) == nil && decoded.Len() == 2, "null should leave the set unchanged")
+	TestExpect(t, json.Unmarshal([]byte(`{"a":1}`), &decoded) != nil, "non-array should fail")
+}
+
+func TestOrderedMapOrder(t *testing.T) {
+	var m OrderedMap[string, int]
+	for idx, key := range []string{"a", "b", "c", "d"} {
+		m.Set(key, idx)
+	}
+	m.Set("a", 10)
+	TestExpectf(t, SlicesEqual(m.Keys(), []string{"a", "b", "c", "d"}), "updating should keep the position: %v", m.Keys())
+
+	TestExpect(t, m.MoveToBack("a"), "MoveToBack should find the key")
+	TestExpect(t, m.MoveToFront("d"), "MoveToFront should find the key")
+	TestExpect(t, !m.MoveToFront("x"), "MoveToFront should not find a missing key")
+	TestExpectf(t, SlicesEqual(m.Keys(), []string{"d", "b", "c", "a"}), "unexpected order after moves: %v", m.Keys())
+
+	var seen []string
+	for key := range m.All() {
+		Append(&seen, key)
+		m.Delete(key)
+	}
+	TestExpectf(t, SlicesEqual(seen, []string{"d", "b", "c", "a"}), "deleting during All should not skip keys: %v", seen)
+	TestExpect(t, m.Len() == 0, "all keys should be deleted")
+}
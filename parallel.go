@@ -0,0 +1,156 @@
+package generic
+
+import (
+	"context"
+	"runtime"
+)
+
+// chunks per worker, so that uneven work still gets spread around
+const parallelChunksPerWorker = 4
+
+// parallelChunkSize returns how many workers to use for n items and how many
+// items go in each chunk
+func parallelChunkSize(n int, workers int, jq *JobQueue) (int, int) {
+	if jq != nil {
+		workers = jq.WorkerCount()
+	}
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	chunkSize := max(1, (n+workers*parallelChunksPerWorker-1)/(workers*parallelChunksPerWorker))
+	return workers, chunkSize
+}
+
+// parallelChunks splits [0, n) into chunks of the size given by
+// parallelChunkSize and calls fn for each chunk through a task group, either
+// on its own goroutines (at most `workers` at a time) or on the job queue.
+// Returns the first error, after which the remaining chunks are skipped and
+// the context passed to fn is canceled.
+func parallelChunks(ctx context.Context, n int, workers int, jq *JobQueue, fn func(ctx context.Context, start, end int) error) error {
+	workers, chunkSize := parallelChunkSize(n, workers, jq)
+
+	g, gctx := NewTaskGroupCtx(ctx, workers)
+	if jq != nil {
+		g.UseQueue(jq)
+	}
+	stopped := false
+	for start := 0; start < n; start += chunkSize {
+		if gctx.Err() != nil {
+			stopped = true
+			break
+		}
+		end := min(start+chunkSize, n)
+		g.Go(func() error {
+			return fn(gctx, start, end)
+		})
+	}
+	if err := g.Wait(); err != nil || stopped {
+		// the first failure (or the parent context) is what canceled gctx
+		return context.Cause(gctx)
+	}
+	return nil
+}
+
+func parallelMap[T, U any](ctx context.Context, in []T, workers int, jq *JobQueue, fn func(ctx context.Context, item T) (U, error)) ([]U, error) {
+	out := make([]U, len(in))
+	err := parallelChunks(ctx, len(in), workers, jq, func(ctx context.Context, start, end int) error {
+		for i := start; i < end; i++ {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			var err error
+			out[i], err = fn(ctx, in[i])
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ParallelMap calls fn on every item of the list using up to `workers`
+// goroutines (0 means GOMAXPROCS), and returns the results in the same order
+// as the input. The list is split into chunks so each goroutine handles a run
+// of items. Stops at the first error and returns it.
+func ParallelMap[T, U any](ctx context.Context, in []T, workers int, fn func(ctx context.Context, item T) (U, error)) ([]U, error) {
+	return parallelMap(ctx, in, workers, nil, fn)
+}
+
+// ParallelMapOn is like ParallelMap but runs on the job queue's workers. It
+// must not be called from a job of the same queue.
+func ParallelMapOn[T, U any](ctx context.Context, jq *JobQueue, in []T, fn func(ctx context.Context, item T) (U, error)) ([]U, error) {
+	return parallelMap(ctx, in, 0, jq, fn)
+}
+
+func parallelForEach[T any](ctx context.Context, in []T, workers int, jq *JobQueue, fn func(ctx context.Context, item T) error) error {
+	return parallelChunks(ctx, len(in), workers, jq, func(ctx context.Context, start, end int) error {
+		for i := start; i < end; i++ {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := fn(ctx, in[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ParallelForEach is like ParallelMap but with no results
+func ParallelForEach[T any](ctx context.Context, in []T, workers int, fn func(ctx context.Context, item T) error) error {
+	return parallelForEach(ctx, in, workers, nil, fn)
+}
+
+// ParallelForEachOn is like ParallelForEach but runs on the job queue's
+// workers. It must not be called from a job of the same queue.
+func ParallelForEachOn[T any](ctx context.Context, jq *JobQueue, in []T, fn func(ctx context.Context, item T) error) error {
+	return parallelForEach(ctx, in, 0, jq, fn)
+}
+
+func parallelReduce[T, U any](ctx context.Context, in []T, workers int, jq *JobQueue, identity U, reduce func(acc U, item T) (U, error), combine func(a, b U) U) (U, error) {
+	_, chunkSize := parallelChunkSize(len(in), workers, jq)
+	partials := make([]U, (len(in)+chunkSize-1)/chunkSize) // one result per chunk
+	err := parallelChunks(ctx, len(in), workers, jq, func(ctx context.Context, start, end int) error {
+		acc := identity
+		for i := start; i < end; i++ {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			var err error
+			acc, err = reduce(acc, in[i])
+			if err != nil {
+				return err
+			}
+		}
+		partials[start/chunkSize] = acc
+		return nil
+	})
+	if err != nil {
+		return identity, err
+	}
+	// combine the chunk results in order, so combine only needs to be
+	// associative, not commutative
+	acc := identity
+	for _, partial := range partials {
+		acc = combine(acc, partial)
+	}
+	return acc, nil
+}
+
+// ParallelReduce reduces chunks of the list in parallel, each starting from
+// identity, then combines the chunk results in order. combine must be
+// associative and identity must be its identity element (e.g. 0 for sums).
+// Stops at the first error and returns it.
+func ParallelReduce[T, U any](ctx context.Context, in []T, workers int, identity U, reduce func(acc U, item T) (U, error), combine func(a, b U) U) (U, error) {
+	return parallelReduce(ctx, in, workers, nil, identity, reduce, combine)
+}
+
+// ParallelReduceOn is like ParallelReduce but runs on the job queue's workers.
+// It must not be called from a job of the same queue.
+func ParallelReduceOn[T, U any](ctx context.Context, jq *JobQueue, in []T, identity U, reduce func(acc U, item T) (U, error), combine func(a, b U) U) (U, error) {
+	return parallelReduce(ctx, in, 0, jq, identity, reduce, combine)
+}
@@ -0,0 +1,67 @@
+package generic
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+)
+
+func TestParallelMapOrder(t *testing.T) {
+	in := make([]int, 1000)
+	for i := range in {
+		in[i] = i
+	}
+	square := func(ctx context.Context, x int) (int, error) {
+		return x * x, nil
+	}
+	out, err := ParallelMap(context.Background(), in, 4, square)
+	TestExpect(t, err == nil, "ParallelMap should not fail")
+	for i := range out {
+		TestExpectf(t, out[i] == i*i, "out[%d] should be %d, got %d", i, i*i, out[i])
+	}
+
+	jq := MakeJobQueue(3)
+	out, err = ParallelMapOn(context.Background(), jq, in, square)
+	TestExpect(t, err == nil && out[999] == 999*999, "ParallelMapOn should map in order")
+}
+
+func TestParallelForEachStopsEarly(t *testing.T) {
+	in := make([]int, 10000)
+	boom := errors.New("boom")
+	var calls atomic.Int32
+	err := ParallelForEach(context.Background(), in, 2, func(ctx context.Context, x int) error {
+		if calls.Add(1) == 10 {
+			return boom
+		}
+		return nil
+	})
+	TestExpectf(t, err == boom, "expected the task error, got %v", err)
+	TestExpectf(t, calls.Load() < int32(len(in)), "should stop early, but ran %d times", calls.Load())
+}
+
+func TestParallelReduceOrder(t *testing.T) {
+	in := make([]string, 500)
+	for i := range in {
+		in[i] = string(rune('a' + i%26))
+	}
+	concat := func(acc string, item string) (string, error) {
+		return acc + item, nil
+	}
+	combine := func(a, b string) string {
+		return a + b
+	}
+	// concatenation isn't commutative, so this checks the chunks are combined in order
+	out, err := ParallelReduce(context.Background(), in, 4, "", concat, combine)
+	expected := ""
+	for _, item := range in {
+		expected += item
+	}
+	TestExpect(t, err == nil && out == expected, "ParallelReduce should combine the chunks in order")
+
+	out, err = ParallelReduceOn(context.Background(), MakeJobQueue(3), in, "", concat, combine)
+	TestExpect(t, err == nil && out == expected, "ParallelReduceOn should combine the chunks in order")
+
+	out, err = ParallelReduce(context.Background(), nil, 4, "", concat, combine)
+	TestExpect(t, err == nil && out == "", "reducing nothing should return identity")
+}
@@ -0,0 +1,312 @@
+package generic
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/gob"
+	"encoding/json"
+	"iter"
+	"maps"
+	"reflect"
+	"slices"
+)
+
+// Set is an unordered collection of unique items. The zero value is an empty
+// set ready to use.
+type Set[T comparable] struct {
+	items map[T]struct{}
+}
+
+func NewSet[T comparable]() *Set[T] {
+	return &Set[T]{
+		items: make(map[T]struct{}),
+	}
+}
+
+func NewSetFrom[T comparable](items []T) *Set[T] {
+	set := NewSet[T]()
+	set.Add(items...)
+	return set
+}
+
+func (s *Set[T]) Add(items ...T) {
+	EnsureMapNotNil(&s.items)
+	for _, item := range items {
+		s.items[item] = struct{}{}
+	}
+}
+
+func (s *Set[T]) Has(item T) bool {
+	_, exists := s.items[item]
+	return exists
+}
+
+func (s *Set[T]) Remove(item T) {
+	delete(s.items, item)
+}
+
+func (s *Set[T]) Len() int {
+	return len(s.items)
+}
+
+func (s *Set[T]) Clone() *Set[T] {
+	return &Set[T]{items: maps.Clone(s.items)}
+}
+
+// Clear removes all items from the set
+func (s *Set[T]) Clear() {
+	clear(s.items)
+}
+
+// All returns an iterator over the items, in no particular order
+func (s *Set[T]) All() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for item := range s.items {
+			if !yield(item) {
+				return
+			}
+		}
+	}
+}
+
+// ToSlice returns the items in no particular order. See SortedSlice for a
+// deterministic order.
+func (s *Set[T]) ToSlice() []T {
+	out := make([]T, 0, len(s.items))
+	for item := range s.items {
+		Append(&out, item)
+	}
+	return out
+}
+
+// SortedSlice returns the items of the set in ascending order
+func SortedSlice[T cmp.Ordered](s *Set[T]) []T {
+	out := s.ToSlice()
+	slices.Sort(out)
+	return out
+}
+
+// UnionWith adds all the items of other to s (in place)
+func (s *Set[T]) UnionWith(other *Set[T]) {
+	for item := range other.items {
+		s.Add(item)
+	}
+}
+
+// IntersectWith removes the items of s that are not in other (in place)
+func (s *Set[T]) IntersectWith(other *Set[T]) {
+	for item := range s.items {
+		if !other.Has(item) {
+			delete(s.items, item)
+		}
+	}
+}
+
+// DifferenceWith removes the items of other from s (in place)
+func (s *Set[T]) DifferenceWith(other *Set[T]) {
+	for item := range other.items {
+		delete(s.items, item)
+	}
+}
+
+// SymmetricDifferenceWith makes s hold the items that are in either s or
+// other but not both (in place)
+func (s *Set[T]) SymmetricDifferenceWith(other *Set[T]) {
+	for item := range other.items {
+		if s.Has(item) {
+			delete(s.items, item)
+		} else {
+			s.Add(item)
+		}
+	}
+}
+
+// Union returns a new set with the items that are in s or other
+func (s *Set[T]) Union(other *Set[T]) *Set[T] {
+	out := s.Clone()
+	out.UnionWith(other)
+	return out
+}
+
+// Intersection returns a new set with the items that are in both s and other
+func (s *Set[T]) Intersection(other *Set[T]) *Set[T] {
+	out := NewSet[T]()
+	small, large := s, other
+	if small.Len() > large.Len() {
+		small, large = large, small
+	}
+	for item := range small.items {
+		if large.Has(item) {
+			out.items[item] = struct{}{}
+		}
+	}
+	return out
+}
+
+// Difference returns a new set with the items of s that are not in other
+func (s *Set[T]) Difference(other *Set[T]) *Set[T] {
+	return s.Filter(func(item T) bool {
+		return !other.Has(item)
+	})
+}
+
+// SymmetricDifference returns a new set with the items that are in either s
+// or other but not both
+func (s *Set[T]) SymmetricDifference(other *Set[T]) *Set[T] {
+	out := s.Clone()
+	out.SymmetricDifferenceWith(other)
+	return out
+}
+
+// IsSubset reports whether every item of s is in other
+func (s *Set[T]) IsSubset(other *Set[T]) bool {
+	if s.Len() > other.Len() {
+		return false
+	}
+	for item := range s.items {
+		if !other.Has(item) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsSuperset reports whether every item of other is in s
+func (s *Set[T]) IsSuperset(other *Set[T]) bool {
+	return other.IsSubset(s)
+}
+
+// Filter returns a new set with the items for which keep returns true
+func (s *Set[T]) Filter(keep func(item T) bool) *Set[T] {
+	out := NewSet[T]()
+	for item := range s.items {
+		if keep(item) {
+			out.items[item] = struct{}{}
+		}
+	}
+	return out
+}
+
+// Retain removes the items for which keep returns false (in place)
+func (s *Set[T]) Retain(keep func(item T) bool) {
+	for item := range s.items {
+		if !keep(item) {
+			delete(s.items, item)
+		}
+	}
+}
+
+func SetsEqual[T comparable](s1 *Set[T], s2 *Set[T]) bool {
+	for item := range s1.items {
+		if !s2.Has(item) {
+			return false
+		}
+	}
+	for item := range s2.items {
+		if !s1.Has(item) {
+			return false
+		}
+	}
+	return true
+}
+
+// sortedItems returns the items in a deterministic order: ascending if T's
+// underlying type is ordered (numbers and strings), otherwise ordered by their
+// JSON encoding
+func (s *Set[T]) sortedItems() ([]T, error) {
+	items := s.ToSlice()
+	if len(items) < 2 {
+		return items, nil
+	}
+	value := func(item T) reflect.Value {
+		return reflect.ValueOf(&item).Elem()
+	}
+	switch reflect.TypeFor[T]().Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		slices.SortFunc(items, func(a, b T) int {
+			return cmp.Compare(value(a).Int(), value(b).Int())
+		})
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		slices.SortFunc(items, func(a, b T) int {
+			return cmp.Compare(value(a).Uint(), value(b).Uint())
+		})
+	case reflect.Float32, reflect.Float64:
+		slices.SortFunc(items, func(a, b T) int {
+			return cmp.Compare(value(a).Float(), value(b).Float())
+		})
+	case reflect.String:
+		slices.SortFunc(items, func(a, b T) int {
+			return cmp.Compare(value(a).String(), value(b).String())
+		})
+	default:
+		encoded := make(map[T][]byte, len(items))
+		for _, item := range items {
+			data, err := json.Marshal(item)
+			if err != nil {
+				return nil, err
+			}
+			encoded[item] = data
+		}
+		slices.SortFunc(items, func(a, b T) int {
+			return bytes.Compare(encoded[a], encoded[b])
+		})
+	}
+	return items, nil
+}
+
+func (s *Set[T]) replaceWith(items []T) {
+	s.items = make(map[T]struct{}, len(items))
+	s.Add(items...)
+}
+
+// MarshalJSON encodes the set as a JSON array, sorted deterministically
+func (s Set[T]) MarshalJSON() ([]byte, error) {
+	items, err := s.sortedItems()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(items)
+}
+
+// UnmarshalJSON decodes a JSON array, replacing the contents of the set
+func (s *Set[T]) UnmarshalJSON(data []byte) error {
+	var items []T
+	if err := json.Unmarshal(data, &items); err != nil {
+		return err
+	}
+	s.replaceWith(items)
+	return nil
+}
+
+// MarshalText encodes the set the same way as MarshalJSON, so the text form
+// is a JSON array like `[1,2,3]`, not a plain list
+func (s Set[T]) MarshalText() ([]byte, error) {
+	return s.MarshalJSON()
+}
+
+// UnmarshalText decodes the JSON array produced by MarshalText
+func (s *Set[T]) UnmarshalText(data []byte) error {
+	return s.UnmarshalJSON(data)
+}
+
+// GobEncode encodes the set as a gob encoded slice of its items
+func (s Set[T]) GobEncode() ([]byte, error) {
+	items, err := s.sortedItems()
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(items); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (s *Set[T]) GobDecode(data []byte) error {
+	var items []T
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&items); err != nil {
+		return err
+	}
+	s.replaceWith(items)
+	return nil
+}
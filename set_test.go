@@ -0,0 +1,204 @@
+package generic
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"testing"
+)
+
+func TestSetZeroValue(t *testing.T) {
+	var s Set[string]
+	TestExpect(t, !s.Has("a"), "empty set should not have items")
+	s.Add("a", "b")
+	TestExpect(t, s.Has("a") && s.Has("b"), "set should have added items")
+	TestExpectf(t, s.Len() == 2, "expected 2 items, got %d", s.Len())
+}
+
+// zeroSetFrom builds a set starting from the zero value, so empty inputs
+// leave it with no map at all
+func zeroSetFrom(items []int) *Set[int] {
+	s := new(Set[int])
+	if len(items) > 0 {
+		s.Add(items...)
+	}
+	return s
+}
+
+func TestSetAlgebra(t *testing.T) {
+	cases := []struct {
+		name                    string
+		a, b                    []int
+		union, inter, diff, sym []int
+		subset                  bool
+	}{
+		{"overlap", []int{1, 2, 3}, []int{2, 3, 4}, []int{1, 2, 3, 4}, []int{2, 3}, []int{1}, []int{1, 4}, false},
+		{"disjoint", []int{1, 2}, []int{3}, []int{1, 2, 3}, nil, []int{1, 2}, []int{1, 2, 3}, false},
+		{"subset", []int{2}, []int{1, 2, 3}, []int{1, 2, 3}, []int{2}, nil, []int{1, 3}, true},
+		{"equal", []int{1, 2}, []int{2, 1}, []int{1, 2}, []int{1, 2}, nil, nil, true},
+		{"empty a", nil, []int{1}, []int{1}, nil, nil, []int{1}, true},
+		{"empty b", []int{1}, nil, []int{1}, nil, []int{1}, []int{1}, false},
+		{"both empty", nil, nil, nil, nil, nil, nil, true},
+	}
+	check := func(name string, op string, got *Set[int], expected []int) {
+		TestExpectf(t, SlicesEqual(SortedSlice(got), SortedSlice(NewSetFrom(expected))), "%s: %s gave %v, expected %v", name, op, SortedSlice(got), expected)
+	}
+	for _, c := range cases {
+		a, b := zeroSetFrom(c.a), zeroSetFrom(c.b)
+		check(c.name, "Union", a.Union(b), c.union)
+		check(c.name, "Intersection", a.Intersection(b), c.inter)
+		check(c.name, "Difference", a.Difference(b), c.diff)
+		check(c.name, "SymmetricDifference", a.SymmetricDifference(b), c.sym)
+		check(c.name, "operands after non in-place ops", a, c.a)
+		TestExpectf(t, a.IsSubset(b) == c.subset, "%s: IsSubset should be %v", c.name, c.subset)
+		TestExpectf(t, b.IsSuperset(a) == c.subset, "%s: IsSuperset should be %v", c.name, c.subset)
+
+		inPlace := zeroSetFrom(c.a)
+		inPlace.UnionWith(b)
+		check(c.name, "UnionWith", inPlace, c.union)
+		inPlace = zeroSetFrom(c.a)
+		inPlace.IntersectWith(b)
+		check(c.name, "IntersectWith", inPlace, c.inter)
+		inPlace = zeroSetFrom(c.a)
+		inPlace.DifferenceWith(b)
+		check(c.name, "DifferenceWith", inPlace, c.diff)
+		inPlace = zeroSetFrom(c.a)
+		inPlace.SymmetricDifferenceWith(b)
+		check(c.name, "SymmetricDifferenceWith", inPlace, c.sym)
+		check(c.name, "other operand after in-place ops", b, c.b)
+	}
+}
+
+func TestSetFilter(t *testing.T) {
+	s := NewSetFrom([]int{1, 2, 3, 4, 5, 6})
+	even := func(item int) bool { return item%2 == 0 }
+	filtered := s.Filter(even)
+	TestExpectf(t, SlicesEqual(SortedSlice(filtered), []int{2, 4, 6}), "Filter should keep the even items: %v", SortedSlice(filtered))
+	TestExpect(t, s.Len() == 6, "Filter should not change the set")
+
+	s.Retain(even)
+	TestExpectf(t, SlicesEqual(SortedSlice(s), []int{2, 4, 6}), "Retain should drop the odd items: %v", SortedSlice(s))
+
+	var zero Set[int]
+	zero.Retain(even)
+	TestExpect(t, zero.Filter(even).Len() == 0 && zero.Len() == 0, "Filter and Retain should work on the zero value")
+	TestExpect(t, SetsEqual(&zero, NewSet[int]()), "a zero set should equal an empty one")
+	TestExpect(t, !SetsEqual(s, filtered.Union(NewSetFrom([]int{8}))), "sets with different items should not be equal")
+}
+
+type setPoint struct {
+	X, Y int
+}
+
+func TestSetEncoding(t *testing.T) {
+	ints := NewSetFrom([]int{30, -5, 10, 2})
+	data, err := json.Marshal(ints)
+	TestExpect(t, err == nil, "marshal should succeed")
+	TestExpectf(t, string(data) == `[-5,2,10,30]`, "ints should be sorted numerically: %s", data)
+	for range 10 {
+		again, _ := json.Marshal(ints.Clone())
+		if !TestExpect(t, bytes.Equal(again, data), "encoding should be deterministic") {
+			break
+		}
+	}
+	var decoded Set[int]
+	TestExpect(t, json.Unmarshal(data, &decoded) == nil && SetsEqual(&decoded, ints), "JSON should round trip")
+
+	text, err := ints.MarshalText()
+	TestExpectf(t, err == nil && string(text) == string(data), "the text form should be the JSON array: %s", text)
+	var fromText Set[int]
+	TestExpect(t, fromText.UnmarshalText(text) == nil && SetsEqual(&fromText, ints), "text should round trip")
+
+	points := NewSetFrom([]setPoint{{2, 1}, {1, 2}, {1, 1}})
+	data, err = json.Marshal(points)
+	TestExpect(t, err == nil, "marshal of a struct set should succeed")
+	// ordered by the JSON encoding of each item
+	TestExpectf(t, string(data) == `[{"X":1,"Y":1},{"X":1,"Y":2},{"X":2,"Y":1}]`, "structs should be sorted by their encoding: %s", data)
+	var decodedPoints Set[setPoint]
+	TestExpect(t, json.Unmarshal(data, &decodedPoints) == nil && SetsEqual(&decodedPoints, points), "struct set should round trip")
+
+	// inside another value, where the zero value and pointers matter
+	type config struct {
+		Tags Set[string]
+		IDs  *Set[int]
+	}
+	in := config{IDs: ints}
+	in.Tags.Add("b", "a")
+	data, err = json.Marshal(in)
+	TestExpectf(t, err == nil && string(data) == `{"Tags":["a","b"],"IDs":[-5,2,10,30]}`, "unexpected nested encoding: %s", data)
+	var out config
+	TestExpect(t, json.Unmarshal(data, &out) == nil && SetsEqual(&out.Tags, &in.Tags) && SetsEqual(out.IDs, ints), "nested sets should round trip")
+
+	TestExpect(t, json.Unmarshal([]byte(`{"a":1}`), &decoded) != nil, "non-array JSON should fail")
+}
+
+func TestSetGob(t *testing.T) {
+	ints := NewSetFrom([]int{3, 1, 2})
+	var buf1, buf2 bytes.Buffer
+	TestExpect(t, gob.NewEncoder(&buf1).Encode(ints) == nil, "gob encode should succeed")
+	TestExpect(t, gob.NewEncoder(&buf2).Encode(ints.Clone()) == nil, "gob encode should succeed")
+	TestExpect(t, bytes.Equal(buf1.Bytes(), buf2.Bytes()), "gob encoding should be deterministic")
+	var decoded Set[int]
+	TestExpect(t, gob.NewDecoder(&buf1).Decode(&decoded) == nil && SetsEqual(&decoded, ints), "int set should round trip through gob")
+
+	points := NewSetFrom([]setPoint{{1, 2}, {3, 4}})
+	var buf bytes.Buffer
+	TestExpect(t, gob.NewEncoder(&buf).Encode(points) == nil, "gob encode of a struct set should succeed")
+	var decodedPoints Set[setPoint]
+	TestExpect(t, gob.NewDecoder(&buf).Decode(&decodedPoints) == nil && SetsEqual(&decodedPoints, points), "struct set should round trip through gob")
+}
+
+// boolSet is the layout Set used to have, kept here to compare against
+type boolSet[T comparable] struct {
+	Map map[T]bool
+}
+
+func (s *boolSet[T]) Add(item T) {
+	s.Map[item] = true
+}
+
+func (s *boolSet[T]) Has(item T) bool {
+	return s.Map[item]
+}
+
+const benchSetSize = 100_000
+
+func BenchmarkSetAdd(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		var s Set[int]
+		for n := 0; n < benchSetSize; n++ {
+			s.Add(n)
+		}
+	}
+}
+
+func BenchmarkBoolSetAdd(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		s := boolSet[int]{Map: make(map[int]bool)}
+		for n := 0; n < benchSetSize; n++ {
+			s.Add(n)
+		}
+	}
+}
+
+func BenchmarkSetHas(b *testing.B) {
+	var s Set[int]
+	for n := 0; n < benchSetSize; n++ {
+		s.Add(n)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.Has(i % (2 * benchSetSize))
+	}
+}
+
+func BenchmarkBoolSetHas(b *testing.B) {
+	s := boolSet[int]{Map: make(map[int]bool)}
+	for n := 0; n < benchSetSize; n++ {
+		s.Add(n)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.Has(i % (2 * benchSetSize))
+	}
+}
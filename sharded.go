@@ -0,0 +1,124 @@
+package generic
+
+import (
+	"hash/maphash"
+	"maps"
+	"runtime"
+)
+
+// ShardedSyncMap has the same API as SyncMap, but splits the keys over several
+// SyncMaps (shards) each with its own lock, to reduce lock contention when many
+// goroutines write at the same time.
+type ShardedSyncMap[K comparable, V any] struct {
+	shards []*SyncMap[K, V]
+	hash   func(key K) uint64
+}
+
+// NewShardedSyncMap creates a sharded map that hashes keys with hash/maphash.
+// A shardCount of 0 or less picks a default based on GOMAXPROCS.
+func NewShardedSyncMap[K comparable, V any](shardCount int) *ShardedSyncMap[K, V] {
+	seed := maphash.MakeSeed()
+	return NewShardedSyncMapHash[K, V](shardCount, func(key K) uint64 {
+		return maphash.Comparable(seed, key)
+	})
+}
+
+// NewShardedSyncMapHash creates a sharded map with a custom hash function
+func NewShardedSyncMapHash[K comparable, V any](shardCount int, hash func(key K) uint64) *ShardedSyncMap[K, V] {
+	if shardCount <= 0 {
+		shardCount = 4 * runtime.GOMAXPROCS(0)
+	}
+	m := &ShardedSyncMap[K, V]{
+		shards: make([]*SyncMap[K, V], shardCount),
+		hash:   hash,
+	}
+	for i := range m.shards {
+		m.shards[i] = NewSyncMap[K, V]()
+	}
+	return m
+}
+
+func (m *ShardedSyncMap[K, V]) shard(key K) *SyncMap[K, V] {
+	return m.shards[m.hash(key)%uint64(len(m.shards))]
+}
+
+func (m *ShardedSyncMap[K, V]) Get(key K) (V, bool) {
+	return m.shard(key).Get(key)
+}
+
+func (m *ShardedSyncMap[K, V]) Set(key K, value V) {
+	m.shard(key).Set(key, value)
+}
+
+func (m *ShardedSyncMap[K, V]) Delete(key K) {
+	m.shard(key).Delete(key)
+}
+
+func (m *ShardedSyncMap[K, V]) LoadAndDelete(key K) (V, bool) {
+	return m.shard(key).LoadAndDelete(key)
+}
+
+func (m *ShardedSyncMap[K, V]) LoadOrStore(key K, value V) (actual V, loaded bool) {
+	return m.shard(key).LoadOrStore(key, value)
+}
+
+func (m *ShardedSyncMap[K, V]) GetOrCreate(key K, fn func(key K) V) V {
+	return m.shard(key).GetOrCreate(key, fn)
+}
+
+func (m *ShardedSyncMap[K, V]) Compute(key K, fn func(old V, ok bool) (V, bool)) (V, bool) {
+	return m.shard(key).Compute(key, fn)
+}
+
+// Clear clears each shard in turn; it's not atomic across shards
+func (m *ShardedSyncMap[K, V]) Clear() {
+	for _, shard := range m.shards {
+		shard.Clear()
+	}
+}
+
+// Range calls fn for each key and value, snapshotting one shard at a time.
+// Stops when fn returns false.
+func (m *ShardedSyncMap[K, V]) Range(fn func(key K, value V) bool) {
+	for _, shard := range m.shards {
+		for key, value := range shard.Snapshot() {
+			if !fn(key, value) {
+				return
+			}
+		}
+	}
+}
+
+func (m *ShardedSyncMap[K, V]) Len() int {
+	total := 0
+	for _, shard := range m.shards {
+		total += shard.Len()
+	}
+	return total
+}
+
+func (m *ShardedSyncMap[K, V]) Keys() []K {
+	var keys []K
+	for _, shard := range m.shards {
+		Append(&keys, shard.Keys()...)
+	}
+	return keys
+}
+
+func (m *ShardedSyncMap[K, V]) Values() []V {
+	var values []V
+	for _, shard := range m.shards {
+		Append(&values, shard.Values()...)
+	}
+	return values
+}
+
+// Snapshot returns a copy of the map. Shards are copied one at a time, so it's
+// not atomic across shards.
+func (m *ShardedSyncMap[K, V]) Snapshot() map[K]V {
+	out := make(map[K]V)
+	for _, shard := range m.shards {
+		maps.Copy(out, shard.Snapshot())
+	}
+	return out
+}
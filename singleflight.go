@@ -0,0 +1,137 @@
+package generic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var ErrNoLoader = errors.New("SyncMap has no loader")
+
+type flightCall[V any] struct {
+	done   chan struct{}
+	value  V
+	err    error
+	shared bool
+}
+
+// Group coalesces concurrent calls for the same key: while a call for a key
+// is in flight, other callers for that key wait for it and share its result
+// instead of doing the work again. The zero value is ready to use.
+type Group[K comparable, V any] struct {
+	lock  sync.Mutex
+	calls map[K]*flightCall[V]
+}
+
+// join returns the call in flight for the key, or starts a new one; leader is
+// true if the caller is expected to run it
+func (g *Group[K, V]) join(key K) (c *flightCall[V], leader bool) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	if c, found := g.calls[key]; found {
+		c.shared = true
+		return c, false
+	}
+	c = &flightCall[V]{done: make(chan struct{})}
+	EnsureMapNotNil(&g.calls)
+	g.calls[key] = c
+	return c, true
+}
+
+func (g *Group[K, V]) run(key K, c *flightCall[V], fn func() (V, error)) {
+	defer func() {
+		if r := recover(); r != nil {
+			c.err = fmt.Errorf("singleflight: call panicked: %v", r)
+			g.finish(key, c)
+			panic(r)
+		}
+		g.finish(key, c)
+	}()
+	c.value, c.err = fn()
+}
+
+func (g *Group[K, V]) finish(key K, c *flightCall[V]) {
+	g.lock.Lock()
+	delete(g.calls, key)
+	g.lock.Unlock()
+	close(c.done)
+}
+
+// Do runs fn for the key, unless a call for it is already in flight, in which
+// case it waits for that call and returns its result. shared reports whether
+// the result was given to more than one caller.
+func (g *Group[K, V]) Do(key K, fn func() (V, error)) (value V, err error, shared bool) {
+	c, leader := g.join(key)
+	if leader {
+		g.run(key, c, fn)
+	} else {
+		<-c.done
+	}
+	return c.value, c.err, c.shared
+}
+
+// DoCtx is like Do but a caller stops waiting when its context is done. The
+// call itself runs in its own goroutine and keeps going for the other callers;
+// its context carries the values of the first caller's context but is never
+// canceled.
+func (g *Group[K, V]) DoCtx(ctx context.Context, key K, fn func(ctx context.Context) (V, error)) (value V, err error, shared bool) {
+	c, leader := g.join(key)
+	if leader {
+		callCtx := context.WithoutCancel(ctx)
+		go func() {
+			// there's no caller to re-panic into; waiters get the panic as an error
+			defer func() { recover() }()
+			g.run(key, c, func() (V, error) {
+				return fn(callCtx)
+			})
+		}()
+	}
+	select {
+	case <-c.done:
+		return c.value, c.err, c.shared
+	case <-ctx.Done():
+		return value, ctx.Err(), false
+	}
+}
+
+// Entry is like MapEntry for a SyncMap, where the value is created at most once
+// even when many goroutines ask for a missing key at the same time. Values are
+// only stored if create succeeds.
+func (g *Group[K, V]) Entry(m *SyncMap[K, V], key K, create func(key K) (V, error)) (V, error) {
+	if value, found := m.Get(key); found {
+		return value, nil
+	}
+	value, err, _ := g.Do(key, func() (V, error) {
+		// another call may have stored it between our Get and Do
+		if value, found := m.Get(key); found {
+			return value, nil
+		}
+		value, err := create(key)
+		if err == nil {
+			m.Set(key, value)
+		}
+		return value, err
+	})
+	return value, err
+}
+
+// NewSyncMapWithLoader creates a SyncMap whose GetOrLoad uses the loader to
+// fill in missing keys, running it once per key no matter how many goroutines
+// ask for it at the same time
+func NewSyncMapWithLoader[K comparable, V any](loader func(key K) (V, error)) *SyncMap[K, V] {
+	m := NewSyncMap[K, V]()
+	m._loader = loader
+	m._group = new(Group[K, V])
+	return m
+}
+
+// GetOrLoad returns the value for the key, loading it with the map's loader if
+// it's missing. See NewSyncMapWithLoader.
+func (m *SyncMap[K, V]) GetOrLoad(key K) (V, error) {
+	if m._loader == nil {
+		var zero V
+		return zero, ErrNoLoader
+	}
+	return m._group.Entry(m, key, m._loader)
+}
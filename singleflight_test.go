@@ -0,0 +1,159 @@
+package generic
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// joinDelay gives goroutines time to join a call that's in flight
+const joinDelay = 50 * time.Millisecond
+
+func TestGroupDo(t *testing.T) {
+	var g Group[string, int]
+	var calls atomic.Int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	fn := func() (int, error) {
+		if calls.Add(1) == 1 {
+			close(started)
+		}
+		<-release
+		return 42, nil
+	}
+
+	type result struct {
+		value  int
+		err    error
+		shared bool
+	}
+	results := make(chan result, 5)
+	do := func() {
+		value, err, shared := g.Do("key", fn)
+		results <- result{value, err, shared}
+	}
+	go do()
+	<-started
+	for range 4 {
+		go do()
+	}
+	time.Sleep(joinDelay)
+	close(release)
+
+	for range 5 {
+		r := <-results
+		TestExpectf(t, r.value == 42 && r.err == nil && r.shared, "every caller should get the shared result: %+v", r)
+	}
+	TestExpectf(t, calls.Load() == 1, "fn should run once, ran %d times", calls.Load())
+
+	value, _, shared := g.Do("key", func() (int, error) { return 7, nil })
+	TestExpect(t, value == 7 && !shared, "a call after the first one finished should run again, unshared")
+}
+
+func TestGroupDoPanic(t *testing.T) {
+	var g Group[string, int]
+	started := make(chan struct{})
+	release := make(chan struct{})
+	leaderPanic := make(chan any, 1)
+	go func() {
+		defer func() { leaderPanic <- recover() }()
+		g.Do("key", func() (int, error) {
+			close(started)
+			<-release
+			panic("boom")
+		})
+	}()
+	<-started
+	waiterErr := make(chan error, 1)
+	go func() {
+		_, err, _ := g.Do("key", func() (int, error) { return 0, nil })
+		waiterErr <- err
+	}()
+	time.Sleep(joinDelay)
+	close(release)
+
+	TestExpect(t, <-leaderPanic == "boom", "the panic should be re-raised in the leader")
+	err := <-waiterErr
+	TestExpectf(t, err != nil && strings.Contains(err.Error(), "boom"), "the waiter should get the panic as an error: %v", err)
+
+	value, err, _ := g.Do("key", func() (int, error) { return 1, nil })
+	TestExpect(t, value == 1 && err == nil, "the key should be usable after a panic")
+}
+
+func TestGroupDoCtx(t *testing.T) {
+	var g Group[string, string]
+	started := make(chan struct{})
+	release := make(chan struct{})
+	callCtxErr := make(chan error, 1)
+	fn := func(ctx context.Context) (string, error) {
+		close(started)
+		<-release
+		callCtxErr <- ctx.Err()
+		return "done", nil
+	}
+
+	leaderCtx, cancelLeader := context.WithCancel(context.Background())
+	leaderErr := make(chan error, 1)
+	go func() {
+		_, err, _ := g.DoCtx(leaderCtx, "key", fn)
+		leaderErr <- err
+	}()
+	<-started
+
+	var wg sync.WaitGroup
+	var waiterValue string
+	var waiterErr error
+	WaitGroupGo(&wg, func() {
+		waiterValue, waiterErr, _ = g.DoCtx(context.Background(), "key", fn)
+	})
+	time.Sleep(joinDelay)
+
+	cancelLeader()
+	err := <-leaderErr
+	TestExpectf(t, errors.Is(err, context.Canceled), "a canceled caller should get ctx.Err(), got %v", err)
+
+	close(release)
+	wg.Wait()
+	TestExpectf(t, waiterValue == "done" && waiterErr == nil, "the call should finish for the others: %q %v", waiterValue, waiterErr)
+	TestExpect(t, <-callCtxErr == nil, "the call's context should not be canceled with the caller's")
+}
+
+func TestSyncMapGetOrLoad(t *testing.T) {
+	var loads atomic.Int32
+	fail := atomic.Bool{}
+	fail.Store(true)
+	m := NewSyncMapWithLoader(func(key string) (int, error) {
+		loads.Add(1)
+		if fail.Load() {
+			return 0, errors.New("load failed")
+		}
+		time.Sleep(10 * time.Millisecond)
+		return len(key), nil
+	})
+
+	_, err := m.GetOrLoad("abc")
+	TestExpect(t, err != nil, "the loader's error should be returned")
+	_, found := m.Get("abc")
+	TestExpect(t, !found, "nothing should be stored when the loader fails")
+
+	fail.Store(false)
+	loads.Store(0)
+	var wg sync.WaitGroup
+	for range 10 {
+		WaitGroupGo(&wg, func() {
+			value, err := m.GetOrLoad("abc")
+			TestExpectf(t, value == 3 && err == nil, "unexpected load result: %d %v", value, err)
+		})
+	}
+	wg.Wait()
+	TestExpectf(t, loads.Load() == 1, "concurrent loads should run the loader once, ran %d times", loads.Load())
+	value, found := m.Get("abc")
+	TestExpect(t, found && value == 3, "the loaded value should be stored")
+
+	_, err = NewSyncMap[string, int]().GetOrLoad("x")
+	TestExpect(t, errors.Is(err, ErrNoLoader), "a map without a loader should say so")
+}
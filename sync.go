@@ -1,6 +1,8 @@
 package generic
 
 import (
+	"iter"
+	"maps"
 	"slices"
 	"sync"
 )
@@ -10,67 +12,147 @@ import (
 // works
 type SyncOrderedSet[T comparable] struct {
 	items []T
+	index map[T]int // item -> position in items
 	lock  sync.RWMutex
 }
 
+// reindex fixes the positions of the items from `from` onwards; must be called
+// with the write lock held
+func (s *SyncOrderedSet[T]) reindex(from int) {
+	EnsureMapNotNil(&s.index)
+	for idx := from; idx < len(s.items); idx++ {
+		s.index[s.items[idx]] = idx
+	}
+}
+
 func (s *SyncOrderedSet[T]) Add(item T) {
 	s.lock.Lock()
 	defer s.lock.Unlock()
-	idx := slices.Index(s.items, item)
-	if idx == -1 {
-		Append(&s.items, item)
+	if _, found := s.index[item]; !found {
+		EnsureMapNotNil(&s.index)
+		s.index[item] = Append(&s.items, item)
+	}
+}
+
+// InsertAt inserts the item at the given position (clamped to the valid
+// range). Returns false without doing anything if the item is already present.
+func (s *SyncOrderedSet[T]) InsertAt(idx int, item T) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, found := s.index[item]; found {
+		return false
 	}
+	Clamp(0, &idx, len(s.items))
+	InsertAt(&s.items, idx, item)
+	s.reindex(idx)
+	return true
 }
 
-// Caller must ensure no duplicates! (though they don't really hurt)
+// Move moves an existing item to the given position (clamped to the valid
+// range). Returns false if the item is not present.
+func (s *SyncOrderedSet[T]) Move(item T, toIdx int) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	fromIdx, found := s.index[item]
+	if !found {
+		return false
+	}
+	Clamp(0, &toIdx, len(s.items)-1)
+	RemoveAt(&s.items, fromIdx, 1)
+	InsertAt(&s.items, toIdx, item)
+	s.reindex(min(fromIdx, toIdx))
+	return true
+}
+
+// Replace sets the contents of the set to the given items, in order. Later
+// duplicates are dropped.
 func (s *SyncOrderedSet[T]) Replace(items []T) {
 	s.lock.Lock()
 	defer s.lock.Unlock()
-	s.items = slices.Clone(items)
+	s.items = make([]T, 0, len(items))
+	s.index = make(map[T]int, len(items))
+	for _, item := range items {
+		if _, found := s.index[item]; !found {
+			s.index[item] = Append(&s.items, item)
+		}
+	}
 }
 
 func (s *SyncOrderedSet[T]) Has(item T) bool {
 	s.lock.RLock()
 	defer s.lock.RUnlock()
-	return slices.Index(s.items, item) != -1
+	_, found := s.index[item]
+	return found
+}
+
+// Index returns the position of the item, or -1 if it's not present
+func (s *SyncOrderedSet[T]) Index(item T) int {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	if idx, found := s.index[item]; found {
+		return idx
+	}
+	return -1
 }
 
 func (s *SyncOrderedSet[T]) Remove(item T) {
 	s.lock.Lock()
 	defer s.lock.Unlock()
-	idx := slices.Index(s.items, item)
-	if idx == -1 {
-		Append(&s.items, item)
+	idx, found := s.index[item]
+	if found {
+		delete(s.index, item)
+		RemoveAt(&s.items, idx, 1)
+		s.reindex(idx)
 	}
 }
 
+func (s *SyncOrderedSet[T]) Len() int {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return len(s.items)
+}
+
 func (s *SyncOrderedSet[T]) List() []T {
 	s.lock.RLock()
 	defer s.lock.RUnlock()
 	return slices.Clone(s.items)
 }
 
-func WithWriteLock(lock *sync.RWMutex, fn func()) {
+// Snapshot returns an iterator over a copy of the items taken at the time of
+// the call, so the set can be modified while iterating
+func (s *SyncOrderedSet[T]) Snapshot() iter.Seq2[int, T] {
+	return slices.All(s.List())
+}
+
+func WithWriteLock(lock RWLocker, fn func()) {
 	lock.Lock()
 	defer lock.Unlock()
 	fn()
 }
 
 // will try to do the thing if the lock is available
-func WithTryWriteLock(lock *sync.RWMutex, fn func()) {
+func WithTryWriteLock(lock RWLocker, fn func()) {
 	if lock.TryLock() {
 		defer lock.Unlock()
 		fn()
 	}
 }
 
-func WithReadLock(lock *sync.RWMutex, fn func()) {
+// will try to do the thing if the read lock is available
+func WithTryReadLock(lock RWLocker, fn func()) {
+	if lock.TryRLock() {
+		defer lock.RUnlock()
+		fn()
+	}
+}
+
+func WithReadLock(lock RWLocker, fn func()) {
 	lock.RLock()
 	defer lock.RUnlock()
 	fn()
 }
 
-func WithLock(lock *sync.Mutex, fn func()) {
+func WithLock(lock Locker, fn func()) {
 	lock.Lock()
 	defer lock.Unlock()
 	fn()
@@ -87,6 +169,10 @@ func WaitGroupGo(wg *sync.WaitGroup, fn func()) {
 type SyncMap[K comparable, V any] struct {
 	_map  map[K]V
 	_lock *sync.RWMutex
+
+	// only set by NewSyncMapWithLoader
+	_loader func(key K) (V, error)
+	_group  *Group[K, V]
 }
 
 func NewSyncMap[K comparable, V any]() *SyncMap[K, V] {
@@ -115,3 +201,93 @@ func (m *SyncMap[K, V]) Clear() {
 	defer m._lock.Unlock()
 	clear(m._map)
 }
+
+func (m *SyncMap[K, V]) Delete(key K) {
+	m._lock.Lock()
+	defer m._lock.Unlock()
+	delete(m._map, key)
+}
+
+// LoadAndDelete deletes the key, returning its previous value if any
+func (m *SyncMap[K, V]) LoadAndDelete(key K) (V, bool) {
+	m._lock.Lock()
+	defer m._lock.Unlock()
+	value, found := m._map[key]
+	delete(m._map, key)
+	return value, found
+}
+
+// LoadOrStore returns the existing value for the key if present (loaded is
+// true). Otherwise it stores and returns the given value.
+func (m *SyncMap[K, V]) LoadOrStore(key K, value V) (actual V, loaded bool) {
+	m._lock.Lock()
+	defer m._lock.Unlock()
+	if existing, found := m._map[key]; found {
+		return existing, true
+	}
+	m._map[key] = value
+	return value, false
+}
+
+// GetOrCreate is like MapEntry but atomic: fn is called at most once per
+// missing key, with the write lock held, so it must not use the map
+func (m *SyncMap[K, V]) GetOrCreate(key K, fn func(key K) V) V {
+	if value, found := m.Get(key); found {
+		return value
+	}
+	m._lock.Lock()
+	defer m._lock.Unlock()
+	return MapEntry(m._map, key, fn)
+}
+
+// Compute atomically updates the value for the key. fn receives the current
+// value (and whether it exists) and returns the new value; returning false
+// deletes the key instead. fn runs with the write lock held, so it must not
+// use the map.
+func (m *SyncMap[K, V]) Compute(key K, fn func(old V, ok bool) (V, bool)) (V, bool) {
+	m._lock.Lock()
+	defer m._lock.Unlock()
+	old, found := m._map[key]
+	value, keep := fn(old, found)
+	if keep {
+		m._map[key] = value
+	} else {
+		delete(m._map, key)
+	}
+	return value, keep
+}
+
+// Range calls fn for each key and value in a snapshot of the map, so fn is
+// free to modify the map. Stops when fn returns false.
+func (m *SyncMap[K, V]) Range(fn func(key K, value V) bool) {
+	for key, value := range m.Snapshot() {
+		if !fn(key, value) {
+			return
+		}
+	}
+}
+
+func (m *SyncMap[K, V]) Len() int {
+	m._lock.RLock()
+	defer m._lock.RUnlock()
+	return len(m._map)
+}
+
+func (m *SyncMap[K, V]) Keys() []K {
+	m._lock.RLock()
+	defer m._lock.RUnlock()
+	return slices.Collect(maps.Keys(m._map))
+}
+
+func (m *SyncMap[K, V]) Values() []V {
+	m._lock.RLock()
+	defer m._lock.RUnlock()
+	return slices.Collect(maps.Values(m._map))
+}
+
+// Snapshot returns a copy of the map
+func (m *SyncMap[K, V]) Snapshot() map[K]V {
+	m._lock.RLock()
+	defer m._lock.RUnlock()
+	return maps.Clone(m._map)
+}
@@ -0,0 +1,186 @@
+package generic
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestSyncOrderedSet(t *testing.T) {
+	var s SyncOrderedSet[string]
+	s.Add("a")
+	s.Add("b")
+	s.Add("c")
+	s.Add("b")
+	TestExpectf(t, SlicesEqual(s.List(), []string{"a", "b", "c"}), "unexpected items: %v", s.List())
+
+	s.Remove("b")
+	TestExpectf(t, SlicesEqual(s.List(), []string{"a", "c"}), "Remove should delete the item: %v", s.List())
+	TestExpect(t, !s.Has("b"), "removed item should not be present")
+	TestExpect(t, s.Index("c") == 1, "index should be updated after Remove")
+
+	s.Remove("x")
+	TestExpectf(t, s.Len() == 2, "removing a missing item should do nothing: %v", s.List())
+
+	TestExpect(t, s.InsertAt(1, "b"), "InsertAt should insert a new item")
+	TestExpect(t, !s.InsertAt(0, "c"), "InsertAt should not insert an existing item")
+	TestExpectf(t, SlicesEqual(s.List(), []string{"a", "b", "c"}), "unexpected items after InsertAt: %v", s.List())
+
+	TestExpect(t, s.Move("a", 2), "Move should find the item")
+	TestExpectf(t, SlicesEqual(s.List(), []string{"b", "c", "a"}), "unexpected items after Move: %v", s.List())
+	for idx, item := range s.List() {
+		TestExpectf(t, s.Index(item) == idx, "index of %v should be %d", item, idx)
+	}
+
+	s.Replace([]string{"x", "y", "x"})
+	TestExpectf(t, SlicesEqual(s.List(), []string{"x", "y"}), "Replace should drop duplicates: %v", s.List())
+
+	for idx, item := range s.Snapshot() {
+		s.Remove(item)
+		TestExpectf(t, idx < 2, "snapshot should not see later changes")
+	}
+	TestExpect(t, s.Len() == 0, "all items should be removed")
+}
+
+func TestSyncOrderedSetConcurrent(t *testing.T) {
+	const workers = 8
+	const perWorker = 500
+
+	var s SyncOrderedSet[int]
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		WaitGroupGo(&wg, func() {
+			base := w * perWorker
+			for i := 0; i < perWorker; i++ {
+				s.Add(base + i)
+				s.Has(base + i/2)
+				if i%3 == 0 {
+					s.Move(base+i, 0)
+				}
+				if i%5 == 0 {
+					s.InsertAt(i, -(base + i + 1))
+				}
+				if i%2 == 0 {
+					s.Remove(base + i)
+				}
+			}
+			for range s.Snapshot() {
+			}
+		})
+	}
+	wg.Wait()
+
+	items := s.List()
+	TestExpectf(t, len(items) == s.Len(), "Len %d does not match List %d", s.Len(), len(items))
+	seen := make(map[int]bool)
+	for idx, item := range items {
+		TestExpectf(t, !seen[item], "duplicate item %d", item)
+		seen[item] = true
+		TestExpectf(t, s.Index(item) == idx, "index of %d should be %d, got %d", item, idx, s.Index(item))
+	}
+	for w := 0; w < workers; w++ {
+		for i := 0; i < perWorker; i++ {
+			item := w*perWorker + i
+			TestExpectf(t, s.Has(item) == (i%2 != 0), "unexpected membership for %d", item)
+		}
+	}
+}
+
+func TestSyncMap(t *testing.T) {
+	m := NewSyncMap[string, int]()
+	v, loaded := m.LoadOrStore("a", 1)
+	TestExpect(t, v == 1 && !loaded, "LoadOrStore should store a missing key")
+	v, loaded = m.LoadOrStore("a", 2)
+	TestExpect(t, v == 1 && loaded, "LoadOrStore should load an existing key")
+
+	calls := 0
+	create := func(key string) int {
+		calls++
+		return 10
+	}
+	m.GetOrCreate("b", create)
+	m.GetOrCreate("b", create)
+	TestExpectf(t, calls == 1, "GetOrCreate should create once, called %d times", calls)
+
+	m.Compute("a", func(old int, ok bool) (int, bool) {
+		return old + 5, true
+	})
+	v, _ = m.Get("a")
+	TestExpectf(t, v == 6, "Compute should update the value, got %d", v)
+	m.Compute("b", func(old int, ok bool) (int, bool) {
+		return 0, false
+	})
+	TestExpect(t, m.Len() == 1, "Compute returning false should delete the key")
+
+	v, found := m.LoadAndDelete("a")
+	TestExpect(t, v == 6 && found && m.Len() == 0, "LoadAndDelete should return and delete the value")
+}
+
+func TestShardedSyncMap(t *testing.T) {
+	m := NewShardedSyncMap[int, int](8)
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		WaitGroupGo(&wg, func() {
+			for i := 0; i < 1000; i++ {
+				m.Compute(i, func(old int, ok bool) (int, bool) {
+					return old + 1, true
+				})
+			}
+		})
+	}
+	wg.Wait()
+	TestExpectf(t, m.Len() == 1000, "expected 1000 keys, got %d", m.Len())
+	for key, value := range m.Snapshot() {
+		TestExpectf(t, value == 8, "key %d should have been incremented 8 times, got %d", key, value)
+	}
+}
+
+// mixed workload: 3 reads for every write, spread over benchKeys keys
+const benchKeys = 1024
+
+func BenchmarkSyncMapMixed(b *testing.B) {
+	m := NewSyncMap[int, int]()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			key := i % benchKeys
+			if i%4 == 0 {
+				m.Set(key, i)
+			} else {
+				m.Get(key)
+			}
+			i++
+		}
+	})
+}
+
+func BenchmarkShardedSyncMapMixed(b *testing.B) {
+	m := NewShardedSyncMap[int, int](0)
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			key := i % benchKeys
+			if i%4 == 0 {
+				m.Set(key, i)
+			} else {
+				m.Get(key)
+			}
+			i++
+		}
+	})
+}
+
+func BenchmarkStdSyncMapMixed(b *testing.B) {
+	var m sync.Map
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			key := i % benchKeys
+			if i%4 == 0 {
+				m.Store(key, i)
+			} else {
+				m.Load(key)
+			}
+			i++
+		}
+	})
+}
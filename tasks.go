@@ -0,0 +1,122 @@
+package generic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+)
+
+// PanicError is what a task that panicked returns instead
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v\n%s", e.Value, e.Stack)
+}
+
+// Unwrap returns the panic value if it was an error
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// callCatchingPanic runs fn, turning a panic into a *PanicError
+func callCatchingPanic(fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+	return fn()
+}
+
+// TaskGroup is the error collecting successor to WaitGroupGo: it runs tasks in
+// goroutines, optionally limiting how many run at once, and Wait returns all
+// their errors joined. Panics in tasks are turned into *PanicError instead of
+// crashing the process. The zero value is ready to use, with no limit.
+type TaskGroup struct {
+	wg     sync.WaitGroup
+	sem    chan struct{} // nil means no limit
+	cancel context.CancelCauseFunc
+	queue  *JobQueue // if set, tasks run on it instead of their own goroutines
+
+	lock sync.Mutex
+	errs []error
+}
+
+// NewTaskGroup creates a group that runs at most limit tasks at the same time.
+// A limit of 0 or less means no limit.
+func NewTaskGroup(limit int) *TaskGroup {
+	g := new(TaskGroup)
+	if limit > 0 {
+		g.sem = make(chan struct{}, limit)
+	}
+	return g
+}
+
+// NewTaskGroupCtx is like NewTaskGroup but also returns a context derived from
+// ctx that's canceled as soon as a task fails, or when Wait returns. Tasks
+// should watch it to stop early.
+func NewTaskGroupCtx(ctx context.Context, limit int) (*TaskGroup, context.Context) {
+	g := NewTaskGroup(limit)
+	ctx, g.cancel = context.WithCancelCause(ctx)
+	return g, ctx
+}
+
+// UseQueue makes the group submit its tasks to the job queue instead of
+// starting a goroutine for each. The queue's worker count then bounds how many
+// run at once. Don't Wait on such a group from inside a job of the same
+// queue: if all the workers are waiting, nothing is left to run the tasks.
+func (g *TaskGroup) UseQueue(jq *JobQueue) {
+	g.queue = jq
+}
+
+// Go runs the task in a new goroutine (or on the queue; see UseQueue). If the
+// group has a limit and it's reached, Go blocks until a running task finishes.
+func (g *TaskGroup) Go(fn func() error) {
+	if g.queue != nil {
+		g.wg.Add(1)
+		g.queue.Submit(func() {
+			defer g.wg.Done()
+			g.finish(callCatchingPanic(fn))
+		})
+		return
+	}
+	if g.sem != nil {
+		g.sem <- struct{}{}
+	}
+	WaitGroupGo(&g.wg, func() {
+		if g.sem != nil {
+			defer func() { <-g.sem }()
+		}
+		g.finish(callCatchingPanic(fn))
+	})
+}
+
+func (g *TaskGroup) finish(err error) {
+	if err == nil {
+		return
+	}
+	g.lock.Lock()
+	Append(&g.errs, err)
+	g.lock.Unlock()
+	if g.cancel != nil {
+		g.cancel(err)
+	}
+}
+
+// Wait waits for all tasks to finish and returns their errors joined with
+// errors.Join, or nil if none failed
+func (g *TaskGroup) Wait() error {
+	g.wg.Wait()
+	if g.cancel != nil {
+		g.cancel(nil)
+	}
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	return errors.Join(g.errs...)
+}
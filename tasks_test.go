@@ -0,0 +1,112 @@
+package generic
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTaskGroupPanic(t *testing.T) {
+	var g TaskGroup
+	g.Go(func() error {
+		panic("boom")
+	})
+	g.Go(func() error {
+		return nil
+	})
+	err := g.Wait()
+	var panicErr *PanicError
+	if !TestExpectf(t, errors.As(err, &panicErr), "panic should become a *PanicError, got %v", err) {
+		return
+	}
+	TestExpectf(t, panicErr.Value == "boom", "unexpected panic value: %v", panicErr.Value)
+	TestExpect(t, len(panicErr.Stack) > 0, "panic error should have a stack")
+
+	sentinel := errors.New("sentinel")
+	g = TaskGroup{}
+	g.Go(func() error {
+		panic(sentinel)
+	})
+	TestExpect(t, errors.Is(g.Wait(), sentinel), "panicking with an error should unwrap to it")
+}
+
+func TestTaskGroupLimit(t *testing.T) {
+	const limit = 3
+	g := NewTaskGroup(limit)
+	var running, maxRunning atomic.Int32
+	for range 20 {
+		g.Go(func() error {
+			n := running.Add(1)
+			for {
+				prev := maxRunning.Load()
+				if n <= prev || maxRunning.CompareAndSwap(prev, n) {
+					break
+				}
+			}
+			time.Sleep(2 * time.Millisecond)
+			running.Add(-1)
+			return nil
+		})
+	}
+	TestExpect(t, g.Wait() == nil, "no task should fail")
+	TestExpectf(t, maxRunning.Load() <= limit, "at most %d tasks should run at once, saw %d", limit, maxRunning.Load())
+	TestExpectf(t, maxRunning.Load() > 1, "tasks should run concurrently, saw %d", maxRunning.Load())
+}
+
+func TestTaskGroupCancelsOnFirstError(t *testing.T) {
+	first := errors.New("first")
+	g, ctx := NewTaskGroupCtx(context.Background(), 0)
+	failed := make(chan struct{})
+	g.Go(func() error {
+		defer close(failed)
+		return first
+	})
+	g.Go(func() error {
+		<-failed
+		<-ctx.Done()
+		return errors.New("second")
+	})
+	err := g.Wait()
+	TestExpectf(t, errors.Is(context.Cause(ctx), first), "cause should be the first error, got %v", context.Cause(ctx))
+	TestExpect(t, errors.Is(err, first), "joined error should include the first error")
+	TestExpectf(t, err != nil && len(err.(interface{ Unwrap() []error }).Unwrap()) == 2, "both errors should be joined: %v", err)
+}
+
+func TestTaskGroupJoinsErrors(t *testing.T) {
+	errA, errB := errors.New("a"), errors.New("b")
+	g := NewTaskGroup(0)
+	g.Go(func() error { return errA })
+	g.Go(func() error { return nil })
+	g.Go(func() error { return errB })
+	err := g.Wait()
+	TestExpect(t, errors.Is(err, errA) && errors.Is(err, errB), "all errors should be joined")
+
+	var empty TaskGroup
+	TestExpect(t, empty.Wait() == nil, "an empty group should not fail")
+
+	g, ctx := NewTaskGroupCtx(context.Background(), 2)
+	g.Go(func() error { return nil })
+	TestExpect(t, g.Wait() == nil, "a group with no failures should return nil")
+	TestExpect(t, errors.Is(context.Cause(ctx), context.Canceled), "Wait should cancel the group's context")
+}
+
+func TestTaskGroupQueue(t *testing.T) {
+	jq := MakeJobQueue(2)
+	g := NewTaskGroup(0)
+	g.UseQueue(jq)
+	var count atomic.Int32
+	for range 10 {
+		g.Go(func() error {
+			count.Add(1)
+			return nil
+		})
+	}
+	g.Go(func() error {
+		panic("on the queue")
+	})
+	var panicErr *PanicError
+	TestExpect(t, errors.As(g.Wait(), &panicErr), "panics on the queue should be caught too")
+	TestExpect(t, count.Load() == 10, "all tasks should run on the queue")
+}
@@ -0,0 +1,187 @@
+package generic
+
+import (
+	"errors"
+)
+
+var ErrTreeCycle = errors.New("node cannot be parented to itself or its own descendant")
+
+type treeLinks[T any] struct {
+	handle   Handle[T] // the node these links belong to
+	parent   Handle[T]
+	children []Handle[T]
+}
+
+// Tree keeps parent/child relationships between the items of a Manager. Nodes
+// that are deleted from the manager (by any means) are unlinked from the tree
+// automatically, and their children become roots; OnOrphan callbacks are told
+// about them.
+type Tree[T any] struct {
+	manager  *Manager[T]
+	links    []treeLinks[T] // indexed by handle slot
+	onOrphan []func(orphan Handle[T], formerParent Handle[T])
+}
+
+func NewTree[T any](m *Manager[T]) *Tree[T] {
+	t := &Tree[T]{manager: m}
+	m.OnDelete(func(handle Handle[T], item *T) {
+		t.unlink(handle)
+	})
+	return t
+}
+
+// OnOrphan registers a callback for nodes that lose their parent because the
+// parent got deleted without deleting its subtree. It's not called when the
+// whole manager is Reset.
+func (t *Tree[T]) OnOrphan(fn func(orphan Handle[T], formerParent Handle[T])) {
+	Append(&t.onOrphan, fn)
+}
+
+// node returns the links for the handle, or nil if it has none (links left
+// behind by a previous item in the same slot don't count)
+func (t *Tree[T]) node(handle Handle[T]) *treeLinks[T] {
+	if handle.slot < 0 || int(handle.slot) >= len(t.links) {
+		return nil
+	}
+	node := &t.links[handle.slot]
+	if node.handle != handle {
+		return nil
+	}
+	return node
+}
+
+func (t *Tree[T]) ensureNode(handle Handle[T]) *treeLinks[T] {
+	node := t.node(handle)
+	if node == nil {
+		GrowSlice(&t.links, int(handle.slot)+1)
+		node = &t.links[handle.slot]
+		*node = treeLinks[T]{handle: handle}
+	}
+	return node
+}
+
+// SetParent makes child the last child of parent, detaching it from its
+// previous parent if it had one
+func (t *Tree[T]) SetParent(child Handle[T], parent Handle[T]) error {
+	if _, err := t.manager.TryGet(child); err != nil {
+		return err
+	}
+	if _, err := t.manager.TryGet(parent); err != nil {
+		return err
+	}
+	for ancestor := parent; ancestor != (Handle[T]{}); ancestor = t.Parent(ancestor) {
+		if ancestor == child {
+			return ErrTreeCycle
+		}
+	}
+	t.Detach(child)
+	t.ensureNode(child).parent = parent
+	parentNode := t.ensureNode(parent)
+	Append(&parentNode.children, child)
+	return nil
+}
+
+// Parent returns the parent of the node, or the zero handle if it's a root
+func (t *Tree[T]) Parent(handle Handle[T]) Handle[T] {
+	if node := t.node(handle); node != nil {
+		return node.parent
+	}
+	return Handle[T]{}
+}
+
+// Children returns a copy of the node's children list
+func (t *Tree[T]) Children(handle Handle[T]) []Handle[T] {
+	if node := t.node(handle); node != nil {
+		return Clone(node.children)
+	}
+	return nil
+}
+
+// Detach removes the node from its parent, making it a root. Its subtree stays
+// attached to it.
+func (t *Tree[T]) Detach(handle Handle[T]) {
+	node := t.node(handle)
+	if node == nil || node.parent == (Handle[T]{}) {
+		return
+	}
+	if parentNode := t.node(node.parent); parentNode != nil {
+		SliceRemove(&parentNode.children, handle)
+	}
+	Reset(&node.parent)
+}
+
+// unlink is called when a node is deleted from the manager
+func (t *Tree[T]) unlink(handle Handle[T]) {
+	node := t.node(handle)
+	if node == nil {
+		return
+	}
+	t.Detach(handle)
+	children := node.children
+	Reset(node)
+	if t.manager.Resetting() {
+		// the children are going away too; nothing is really orphaned
+		return
+	}
+	for _, child := range children {
+		if childNode := t.node(child); childNode != nil {
+			Reset(&childNode.parent)
+		}
+		for _, fn := range t.onOrphan {
+			fn(child, handle)
+		}
+	}
+}
+
+// WalkDepthFirst visits the subtree under root (including root) in pre-order.
+// The walk stops when visitFn returns false.
+func (t *Tree[T]) WalkDepthFirst(root Handle[T], visitFn func(handle Handle[T], depth int) bool) {
+	t.walkDepthFirst(root, 0, visitFn)
+}
+
+func (t *Tree[T]) walkDepthFirst(handle Handle[T], depth int, visitFn func(handle Handle[T], depth int) bool) bool {
+	if !visitFn(handle, depth) {
+		return false
+	}
+	for _, child := range t.Children(handle) {
+		if !t.walkDepthFirst(child, depth+1, visitFn) {
+			return false
+		}
+	}
+	return true
+}
+
+// WalkBreadthFirst visits the subtree under root (including root) level by
+// level. The walk stops when visitFn returns false.
+func (t *Tree[T]) WalkBreadthFirst(root Handle[T], visitFn func(handle Handle[T], depth int) bool) {
+	level := []Handle[T]{root}
+	for depth := 0; len(level) > 0; depth++ {
+		var next []Handle[T]
+		for _, handle := range level {
+			if !visitFn(handle, depth) {
+				return
+			}
+			if node := t.node(handle); node != nil {
+				Append(&next, node.children...)
+			}
+		}
+		level = next
+	}
+}
+
+// DeleteRecursive deletes root and its whole subtree from the manager
+func (t *Tree[T]) DeleteRecursive(root Handle[T]) error {
+	if _, err := t.manager.TryGet(root); err != nil {
+		return err
+	}
+	var subtree []Handle[T]
+	t.WalkDepthFirst(root, func(handle Handle[T], depth int) bool {
+		Append(&subtree, handle)
+		return true
+	})
+	// delete children before parents so nothing gets orphaned along the way
+	for i := len(subtree) - 1; i >= 0; i-- {
+		t.manager.Delete(subtree[i])
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package generic
+
+import (
+	"errors"
+	"testing"
+)
+
+// buildTree makes root -> (a -> (a1, a2), b -> b1)
+func buildTree(t *testing.T) (*Manager[string], *Tree[string], map[string]Handle[string]) {
+	m := MakeManager[string]()
+	tree := NewTree(m)
+	nodes := make(map[string]Handle[string])
+	for _, name := range []string{"root", "a", "b", "a1", "a2", "b1"} {
+		item, handle := m.Create()
+		*item = name
+		nodes[name] = handle
+	}
+	for _, link := range [][2]string{{"a", "root"}, {"b", "root"}, {"a1", "a"}, {"a2", "a"}, {"b1", "b"}} {
+		if err := tree.SetParent(nodes[link[0]], nodes[link[1]]); err != nil {
+			t.Fatalf("SetParent(%s, %s): %v", link[0], link[1], err)
+		}
+	}
+	return m, tree, nodes
+}
+
+func TestTreeWalks(t *testing.T) {
+	m, tree, nodes := buildTree(t)
+	var depthFirst []string
+	tree.WalkDepthFirst(nodes["root"], func(handle Handle[string], depth int) bool {
+		Append(&depthFirst, *m.GetItem(handle))
+		return true
+	})
+	TestExpectf(t, SlicesEqual(depthFirst, []string{"root", "a", "a1", "a2", "b", "b1"}), "unexpected depth first order: %v", depthFirst)
+
+	var breadthFirst []string
+	var depths []int
+	tree.WalkBreadthFirst(nodes["root"], func(handle Handle[string], depth int) bool {
+		Append(&breadthFirst, *m.GetItem(handle))
+		Append(&depths, depth)
+		return true
+	})
+	TestExpectf(t, SlicesEqual(breadthFirst, []string{"root", "a", "b", "a1", "a2", "b1"}), "unexpected breadth first order: %v", breadthFirst)
+	TestExpectf(t, SlicesEqual(depths, []int{0, 1, 1, 2, 2, 2}), "unexpected depths: %v", depths)
+
+	visited := 0
+	tree.WalkDepthFirst(nodes["root"], func(handle Handle[string], depth int) bool {
+		visited++
+		return visited < 3
+	})
+	TestExpect(t, visited == 3, "returning false should stop the walk")
+}
+
+func TestTreeSetParent(t *testing.T) {
+	_, tree, nodes := buildTree(t)
+	TestExpect(t, errors.Is(tree.SetParent(nodes["root"], nodes["a1"]), ErrTreeCycle), "parenting to a descendant should fail")
+	TestExpect(t, errors.Is(tree.SetParent(nodes["a"], nodes["a"]), ErrTreeCycle), "parenting to itself should fail")
+	TestExpect(t, tree.Parent(nodes["root"]) == Handle[string]{}, "a failed SetParent should change nothing")
+
+	TestExpect(t, tree.SetParent(nodes["a2"], nodes["b"]) == nil, "reparenting should work")
+	TestExpect(t, tree.Parent(nodes["a2"]) == nodes["b"], "a2 should have the new parent")
+	TestExpect(t, SlicesEqual(tree.Children(nodes["a"]), []Handle[string]{nodes["a1"]}), "a2 should be removed from its old parent")
+	TestExpect(t, SlicesEqual(tree.Children(nodes["b"]), []Handle[string]{nodes["b1"], nodes["a2"]}), "a2 should be the last child of b")
+
+	tree.Detach(nodes["b"])
+	TestExpect(t, tree.Parent(nodes["b"]) == Handle[string]{}, "a detached node should be a root")
+	TestExpect(t, len(tree.Children(nodes["b"])) == 2, "Detach should keep the subtree")
+	TestExpect(t, SlicesEqual(tree.Children(nodes["root"]), []Handle[string]{nodes["a"]}), "b should be removed from root")
+}
+
+func TestTreeOrphansAndDelete(t *testing.T) {
+	m, tree, nodes := buildTree(t)
+	var orphans []string
+	tree.OnOrphan(func(orphan Handle[string], formerParent Handle[string]) {
+		TestExpect(t, !m.Valid(formerParent), "the former parent should already be deleted")
+		Append(&orphans, *m.GetItem(orphan))
+	})
+
+	m.Delete(nodes["a"])
+	TestExpectf(t, SlicesEqual(orphans, []string{"a1", "a2"}), "deleting a should orphan its children: %v", orphans)
+	TestExpect(t, tree.Parent(nodes["a1"]) == Handle[string]{}, "orphans should become roots")
+	TestExpect(t, SlicesEqual(tree.Children(nodes["root"]), []Handle[string]{nodes["b"]}), "a should be removed from root")
+
+	orphans = nil
+	TestExpect(t, tree.DeleteRecursive(nodes["root"]) == nil, "DeleteRecursive should succeed")
+	TestExpectf(t, len(orphans) == 0, "DeleteRecursive should not orphan anything: %v", orphans)
+	for _, name := range []string{"root", "b", "b1"} {
+		TestExpectf(t, !m.Valid(nodes[name]), "%s should be deleted", name)
+	}
+	TestExpect(t, m.Valid(nodes["a1"]) && m.Valid(nodes["a2"]), "nodes outside the subtree should survive")
+	TestExpect(t, tree.DeleteRecursive(nodes["root"]) != nil, "deleting a deleted root should fail")
+
+	// a new item reusing a slot shouldn't inherit the old node's links
+	_, fresh := m.Create()
+	TestExpect(t, tree.Parent(fresh) == Handle[string]{} && len(tree.Children(fresh)) == 0, "a reused slot should start with no links")
+}
+
+func TestTreeManagerReset(t *testing.T) {
+	m, tree, nodes := buildTree(t)
+	orphans := 0
+	tree.OnOrphan(func(orphan Handle[string], formerParent Handle[string]) {
+		orphans++
+	})
+	m.Reset()
+	TestExpectf(t, orphans == 0, "Reset should not report orphans, got %d", orphans)
+	TestExpect(t, !m.Resetting(), "Resetting should be false after Reset")
+
+	_, fresh := m.Create()
+	TestExpect(t, fresh.slot == nodes["root"].slot, "slots should be reused after Reset")
+	TestExpect(t, len(tree.Children(fresh)) == 0, "the new node should not see the old links")
+}